@@ -0,0 +1,129 @@
+// Package ai implements computer opponents on top of the game rules.
+package ai
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// Agent chooses moves for whichever side is to move.
+type Agent interface {
+	// Name describes the agent for menus and logs.
+	Name() string
+	// Move picks a legal move for the side to move. It must not modify s.
+	Move(s *game.State) (game.Move, error)
+}
+
+// Difficulty selects how strong the built-in opponent plays.
+type Difficulty int
+
+const (
+	// Easy mostly plays random moves and only sometimes spots a win.
+	Easy Difficulty = iota
+	// Medium looks two plies ahead, so it takes wins and blocks threats
+	// but falls for forks.
+	Medium
+	// Perfect searches to the end of the game where time allows.
+	Perfect
+	// MonteCarlo plays random games instead of searching, the strongest
+	// choice on large boards.
+	MonteCarlo
+	// Learning starts out random and learns from every game it finishes,
+	// see Menace.
+	Learning
+)
+
+// Difficulties lists every level, the fixed ones in increasing strength.
+var Difficulties = []Difficulty{Easy, Medium, Perfect, MonteCarlo, Learning}
+
+func (d Difficulty) String() string {
+	switch d {
+	case Easy:
+		return "Easy"
+	case Medium:
+		return "Medium"
+	case Perfect:
+		return "Perfect"
+	case MonteCarlo:
+		return "MCTS"
+	case Learning:
+		return "MENACE"
+	}
+	return fmt.Sprintf("Difficulty(%d)", int(d))
+}
+
+// CheckRules tells why the level cannot play under rules, if it cannot.
+func (d Difficulty) CheckRules(rules game.Rules) error {
+	if d == Learning {
+		return CheckMenace(rules)
+	}
+	return nil
+}
+
+// ParseDifficulty accepts a level by name, ignoring case.
+func ParseDifficulty(name string) (Difficulty, error) {
+	for _, d := range Difficulties {
+		if strings.EqualFold(name, d.String()) {
+			return d, nil
+		}
+	}
+	return Easy, fmt.Errorf("unknown difficulty %q: want easy, medium, perfect, mcts or menace", name)
+}
+
+// PerfectBudget bounds the thinking time of the Perfect level on boards too
+// large to search exhaustively.
+const PerfectBudget = 3 * time.Second
+
+// DefaultPlayouts is how many random games the MonteCarlo level plays per
+// move unless told otherwise.
+const DefaultPlayouts = 20_000
+
+// MCTSPlayouts and MCTSBudget bound the thinking of the MonteCarlo level as
+// made by New, zero meaning no bound. Commands may change them before
+// creating agents, but at least one must stay set.
+var (
+	MCTSPlayouts = DefaultPlayouts
+	MCTSBudget   = 3 * time.Second
+)
+
+// Seeded makes the agents made by New depend on their seed alone, for
+// commands given a seed to replay: the MonteCarlo level then ignores
+// MCTSBudget.
+var Seeded bool
+
+// New returns the built-in opponent for the difficulty. The seed makes its
+// random choices reproducible.
+func New(d Difficulty, seed int64) Agent {
+	switch d {
+	case MonteCarlo:
+		m := NewMCTS(MCTSPlayouts, MCTSBudget, seed)
+		m.Seeded = Seeded
+		return m
+	case Learning:
+		return NewMenace(MenaceDir, seed)
+	}
+	n := &Negamax{rng: rand.New(rand.NewSource(seed)), name: d.String()}
+	switch d {
+	case Easy:
+		n.MaxDepth = 1
+		n.Blunder = 0.5
+	case Medium:
+		n.MaxDepth = 2
+	default:
+		n.Budget = PerfectBudget
+	}
+	return n
+}
+
+// randomMove picks any legal move.
+func randomMove(s *game.State, rng *rand.Rand) (game.Move, error) {
+	moves := s.LegalMoves()
+	if len(moves) == 0 {
+		return game.Move{}, game.ErrGameOver
+	}
+	return moves[rng.Intn(len(moves))], nil
+}
@@ -0,0 +1,218 @@
+package ai
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// exploration is the UCT constant balancing well-scoring moves against
+// rarely tried ones.
+const exploration = math.Sqrt2
+
+// DefaultWorkers is the number of trees grown at once unless told otherwise.
+// It does not follow the number of CPUs, so a seed plays the same moves on
+// every machine.
+const DefaultWorkers = 4
+
+// MCTS is a Monte Carlo tree search using UCT. Instead of scoring positions
+// it plays random games from them, so it needs no knowledge of the board and
+// copes with boards far too large to search exhaustively.
+//
+// Every worker grows a tree of its own from the same position and their
+// visit counts are added up at the end. With only Playouts set, or Seeded,
+// the moves are reproducible for a given seed and number of workers; a Budget
+// makes them depend on the clock.
+type MCTS struct {
+	// Playouts limits the random games per move, shared by the workers;
+	// zero means no limit.
+	Playouts int
+	// Budget limits the thinking time; zero means no limit.
+	Budget time.Duration
+	// Workers is the number of trees grown at once, DefaultWorkers when
+	// zero.
+	Workers int
+	// Seeded ignores Budget so the moves depend on the seed alone, playing
+	// DefaultPlayouts when Playouts is zero.
+	Seeded bool
+
+	rng  *rand.Rand
+	name string
+}
+
+// NewMCTS returns a search with the given limits. When neither is set it
+// plays DefaultPlayouts per move.
+func NewMCTS(playouts int, budget time.Duration, seed int64) *MCTS {
+	return &MCTS{Playouts: playouts, Budget: budget, rng: rand.New(rand.NewSource(seed)), name: "MCTS"}
+}
+
+func (m *MCTS) Name() string {
+	return m.name
+}
+
+func (m *MCTS) Move(s *game.State) (game.Move, error) {
+	if s.IsTerminal() {
+		return game.Move{}, game.ErrGameOver
+	}
+	moves := candidates(s)
+	if len(moves) == 1 {
+		return moves[0], nil
+	}
+
+	budget := m.Budget
+	if m.Seeded {
+		budget = 0
+	}
+	playouts := m.Playouts
+	if playouts == 0 && budget == 0 {
+		playouts = DefaultPlayouts
+	}
+	workers := m.Workers
+	if workers < 1 {
+		workers = DefaultWorkers
+	}
+	if playouts > 0 {
+		workers = min(workers, playouts)
+	}
+	var deadline time.Time
+	if budget > 0 {
+		deadline = time.Now().Add(budget)
+	}
+
+	// Seeds are drawn up front so the trees do not depend on scheduling
+	trees := make([]*tree, workers)
+	for i := range trees {
+		share := 0
+		if playouts > 0 {
+			share = playouts / workers
+			if i < playouts%workers {
+				share++
+			}
+		}
+		trees[i] = &tree{root: newNode(s, game.Move{}, nil), rng: rand.New(rand.NewSource(m.rng.Int63())), playouts: share, deadline: deadline}
+	}
+
+	var wg sync.WaitGroup
+	for _, t := range trees {
+		wg.Add(1)
+		go func(t *tree) {
+			defer wg.Done()
+			t.grow(s)
+		}(t)
+	}
+	wg.Wait()
+
+	// Play the move visited most, the one the search trusts most
+	visits := make(map[game.Move]int)
+	for _, t := range trees {
+		for _, child := range t.root.children {
+			visits[child.move] += child.visits
+		}
+	}
+	best := moves[0]
+	for _, move := range moves {
+		if visits[move] > visits[best] {
+			best = move
+		}
+	}
+	return best, nil
+}
+
+// tree is the search of one worker.
+type tree struct {
+	root     *node
+	rng      *rand.Rand
+	playouts int
+	deadline time.Time
+}
+
+type node struct {
+	move   game.Move
+	parent *node
+	// mover is the side that played move
+	mover    game.Player
+	children []*node
+	untried  []game.Move
+	visits   int
+	// score counts wins of mover as one and draws as a half
+	score float64
+}
+
+func newNode(s *game.State, m game.Move, parent *node) *node {
+	n := &node{move: m, parent: parent, mover: s.Turn().Opponent()}
+	if !s.IsTerminal() {
+		n.untried = candidates(s)
+	}
+	return n
+}
+
+// grow runs playouts from s until the tree's playouts or time run out.
+func (t *tree) grow(s *game.State) {
+	for i := 0; t.playouts == 0 || i < t.playouts; i++ {
+		if !t.deadline.IsZero() && i%checkEvery == 0 && time.Now().After(t.deadline) {
+			return
+		}
+		t.playout(s.Clone())
+	}
+}
+
+// playout walks down the tree by UCT, adds one node, finishes the game at
+// random and scores every node on the way.
+func (t *tree) playout(pos *game.State) {
+	n := t.root
+	for len(n.untried) == 0 && len(n.children) > 0 {
+		n = n.uct()
+		_ = pos.Apply(n.move)
+	}
+
+	if len(n.untried) > 0 {
+		i := t.rng.Intn(len(n.untried))
+		m := n.untried[i]
+		n.untried[i] = n.untried[len(n.untried)-1]
+		n.untried = n.untried[:len(n.untried)-1]
+		_ = pos.Apply(m)
+		child := newNode(pos, m, n)
+		n.children = append(n.children, child)
+		n = child
+	}
+
+	winner := t.rollout(pos)
+	for ; n != nil; n = n.parent {
+		n.visits++
+		switch winner {
+		case n.mover:
+			n.score++
+		case game.Empty:
+			n.score += 0.5
+		}
+	}
+}
+
+// rollout plays random moves until the game ends and returns the winner.
+func (t *tree) rollout(pos *game.State) game.Player {
+	moves := pos.LegalMoves()
+	t.rng.Shuffle(len(moves), func(i, j int) { moves[i], moves[j] = moves[j], moves[i] })
+	for _, m := range moves {
+		if pos.IsTerminal() {
+			break
+		}
+		_ = pos.Apply(m)
+	}
+	return pos.Winner()
+}
+
+// uct picks the child with the best upper confidence bound.
+func (n *node) uct() *node {
+	best, bestValue := n.children[0], math.Inf(-1)
+	logVisits := math.Log(float64(n.visits))
+	for _, child := range n.children {
+		value := child.score/float64(child.visits) + exploration*math.Sqrt(logVisits/float64(child.visits))
+		if value > bestValue {
+			best, bestValue = child, value
+		}
+	}
+	return best
+}
@@ -0,0 +1,57 @@
+package ai
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game/gametest"
+)
+
+func TestMCTSTactics(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules game.Rules
+		moves []string
+		// want lists the moves accepted
+		want []string
+	}{
+		{"takes the win", game.Standard(), []string{"a1", "b1", "a2", "b2"}, []string{"a3"}},
+		{"blocks the loss", game.Standard(), []string{"a1", "b2", "a2"}, []string{"a3"}},
+		{"wins on a wide board", game.Rules{Size: 7, K: 4}, []string{"c4", "c5", "d4", "d5", "e4", "e5"}, []string{"b4", "f4"}},
+		{"keeps off the line under misère", game.Rules{Size: 3, K: 3, Misere: true}, []string{"a1", "b1", "a2", "c2", "c1", "b3"}, []string{"b2", "c3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMCTS(5000, time.Minute, 1)
+			m.Seeded = true
+			move, err := m.Move(gametest.Position(t, tt.rules, tt.moves...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Contains(tt.want, move.String()) {
+				t.Errorf("Move() = %v, want one of %v", move, tt.want)
+			}
+		})
+	}
+}
+
+func TestMCTSSeeded(t *testing.T) {
+	s := gametest.Position(t, game.Rules{Size: 5, K: 4}, "c3", "b2")
+	var first game.Move
+	for i := 0; i < 3; i++ {
+		// A budget too short to finish must not change the move once seeded
+		m := NewMCTS(3000, time.Nanosecond, 7)
+		m.Seeded = true
+		move, err := m.Move(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = move
+		} else if move != first {
+			t.Fatalf("run %d: Move() = %v, want %v as in the first run", i+1, move, first)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// Learner is an agent that improves from finished games.
+type Learner interface {
+	Agent
+	// Learn reinforces the moves of a game under rules that winner won,
+	// game.Empty for a draw.
+	Learn(rules game.Rules, moves []game.Move, winner game.Player) error
+	// Save keeps what was learnt for later games.
+	Save() error
+}
+
+// MenaceMaxSize is the largest board the Learning level plays. Larger boards
+// have far too many positions to learn, or to keep.
+const MenaceMaxSize = 3
+
+const (
+	// boxesVersion changes whenever Boxes are kept differently, what was
+	// learnt in another version is forgotten
+	boxesVersion = 2
+	// initialBeads is how many beads every move starts with.
+	initialBeads = 3
+	// The beads added to every move of a won, drawn and lost game
+	winBeads  = 3
+	drawBeads = 1
+	lossBeads = -1
+)
+
+// MenaceDir is where the Learning level made by New keeps what it learnt,
+// one file per board size and line length. When empty it learns in memory
+// only.
+var MenaceDir string
+
+// Menace learns like Donald Michie's matchbox machine: every position it
+// has seen is a box holding beads for each legal move, and it plays a move
+// with a chance proportional to its beads. After a game the moves of the
+// winner gain beads and those of the loser lose some, so good moves become
+// likelier. It learns from the moves of both sides, its opponent's included.
+// Positions that are rotations or reflections of each other share a box, as
+// in the solver.
+type Menace struct {
+	// Dir is where the boxes are loaded from and saved to, nowhere when
+	// empty.
+	Dir string
+
+	rng   *rand.Rand
+	name  string
+	rules game.Rules
+	boxes *Boxes
+}
+
+// Boxes holds the learnt beads of one set of rules.
+type Boxes struct {
+	Version int `json:"version"`
+	// Games counts the games learnt from
+	Games int `json:"games"`
+	// Beads maps a position, as written by boxKey, to the beads of its
+	// empty cells in the order of the key
+	Beads map[string][]int `json:"beads"`
+}
+
+// box is the beads of one position together with the cell of the board
+// every bead stands for.
+type box struct {
+	beads []int
+	cells []int
+}
+
+// CheckMenace tells why the Learning level cannot play under rules, if it
+// cannot.
+func CheckMenace(rules game.Rules) error {
+	if rules.Variant != game.Classic || rules.Size > MenaceMaxSize {
+		return fmt.Errorf("MENACE only learns on boards up to %dx%d", MenaceMaxSize, MenaceMaxSize)
+	}
+	return nil
+}
+
+// NewMenace returns a learner keeping its boxes in dir, or in memory only
+// when dir is empty.
+func NewMenace(dir string, seed int64) *Menace {
+	return &Menace{Dir: dir, rng: rand.New(rand.NewSource(seed)), name: "MENACE"}
+}
+
+func (m *Menace) Name() string {
+	return m.name
+}
+
+// Boxes returns the boxes for rules, loading them when needed.
+func (m *Menace) Boxes(rules game.Rules) (*Boxes, error) {
+	if m.boxes != nil && m.rules == rules {
+		return m.boxes, nil
+	}
+	if err := CheckMenace(rules); err != nil {
+		return nil, err
+	}
+	boxes := &Boxes{Version: boxesVersion, Beads: make(map[string][]int)}
+	if m.Dir != "" {
+		data, err := os.ReadFile(m.Path(rules))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, boxes); err != nil {
+				return nil, fmt.Errorf("%s: %w", m.Path(rules), err)
+			}
+			if boxes.Version != boxesVersion {
+				boxes = &Boxes{Version: boxesVersion, Beads: make(map[string][]int)}
+			}
+		}
+	}
+	m.rules, m.boxes = rules, boxes
+	return boxes, nil
+}
+
+// Path returns the file holding the boxes for rules.
+func (m *Menace) Path(rules game.Rules) string {
+	name := fmt.Sprintf("menace-%dx%d-%d", rules.Size, rules.Size, rules.K)
+	if rules.Misere {
+		name += "-misere"
+	}
+	return filepath.Join(m.Dir, name+".json")
+}
+
+func (m *Menace) Move(s *game.State) (game.Move, error) {
+	if s.IsTerminal() {
+		return game.Move{}, game.ErrGameOver
+	}
+	boxes, err := m.Boxes(s.Rules())
+	if err != nil {
+		return game.Move{}, err
+	}
+	b := boxes.box(s)
+
+	total := 0
+	for _, n := range b.beads {
+		total += n
+	}
+	if total == 0 {
+		// Every move has lost all its beads, start the box afresh
+		for i := range b.beads {
+			b.beads[i] = initialBeads
+		}
+		total = initialBeads * len(b.beads)
+	}
+	pick := m.rng.Intn(total)
+	for i, n := range b.beads {
+		if pick < n {
+			return game.Move{Row: b.cells[i] / s.Size(), Col: b.cells[i] % s.Size()}, nil
+		}
+		pick -= n
+	}
+	return randomMove(s, m.rng)
+}
+
+// Learn reinforces every move of the game for the side that made it. Save
+// keeps the result.
+func (m *Menace) Learn(rules game.Rules, moves []game.Move, winner game.Player) error {
+	boxes, err := m.Boxes(rules)
+	if err != nil {
+		return err
+	}
+	s := game.NewState(rules)
+	for _, move := range moves {
+		b := boxes.box(s)
+		i := slices.Index(b.cells, move.Row*s.Size()+move.Col)
+		if i < 0 {
+			return fmt.Errorf("%w: %v", game.ErrOccupied, move)
+		}
+		switch winner {
+		case s.Turn():
+			b.beads[i] += winBeads
+		case game.Empty:
+			b.beads[i] += drawBeads
+		default:
+			b.beads[i] = max(0, b.beads[i]+lossBeads)
+		}
+		if err := s.Apply(move); err != nil {
+			return err
+		}
+	}
+	boxes.Games++
+	return nil
+}
+
+// Save writes the boxes to Dir, if any.
+func (m *Menace) Save() error {
+	if m.Dir == "" || m.boxes == nil {
+		return nil
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(m.boxes)
+	if err != nil {
+		return err
+	}
+	path := m.Path(m.rules)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// box returns the beads for position s, filling a new box when it is seen
+// for the first time or was kept with the wrong number of beads.
+func (b *Boxes) box(s *game.State) box {
+	key, perm := boxKey(s)
+	var cells []int
+	for i, c := range key {
+		if c == '.' {
+			cells = append(cells, perm[i])
+		}
+	}
+	beads, ok := b.Beads[key]
+	if !ok || len(beads) != len(cells) {
+		beads = make([]int, len(cells))
+		for i := range beads {
+			beads[i] = initialBeads
+		}
+		b.Beads[key] = beads
+	}
+	return box{beads: beads, cells: cells}
+}
+
+// boxKey writes a position one character per cell, row by row, in the
+// smallest of its 8 symmetric forms. Cell i of the key is cell perm[i] of
+// the board.
+func boxKey(s *game.State) (string, []int) {
+	n := s.Size()
+	cells := make([]byte, n*n)
+	for i := range cells {
+		switch s.At(i/n, i%n) {
+		case game.X:
+			cells[i] = 'x'
+		case game.O:
+			cells[i] = 'o'
+		default:
+			cells[i] = '.'
+		}
+	}
+
+	var key string
+	var best []int
+	form := make([]byte, n*n)
+	for _, perm := range game.Symmetries(n) {
+		for i, j := range perm {
+			form[i] = cells[j]
+		}
+		if best == nil || string(form) < key {
+			key, best = string(form), perm
+		}
+	}
+	return key, best
+}
@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"os"
+	"testing"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game/gametest"
+)
+
+func TestCheckMenace(t *testing.T) {
+	if err := CheckMenace(game.Standard()); err != nil {
+		t.Errorf("CheckMenace(3x3) error = %v", err)
+	}
+	for _, rules := range []game.Rules{{Size: 4, K: 3}, game.UltimateRules()} {
+		if err := CheckMenace(rules); err == nil {
+			t.Errorf("CheckMenace(%v) returned no error", rules)
+		}
+		if _, err := NewMenace("", 1).Boxes(rules); err == nil {
+			t.Errorf("Boxes(%v) returned no error", rules)
+		}
+	}
+}
+
+func TestBoxKey(t *testing.T) {
+	// Every corner opening is one position turned or mirrored
+	key, _ := boxKey(gametest.Position(t, game.Standard(), "a1"))
+	for _, corner := range []string{"c1", "a3", "c3"} {
+		if got, _ := boxKey(gametest.Position(t, game.Standard(), corner)); got != key {
+			t.Errorf("boxKey(%s) = %q, want %q as for a1", corner, got, key)
+		}
+	}
+	if got, _ := boxKey(gametest.Position(t, game.Standard(), "b1")); got == key {
+		t.Errorf("boxKey(b1) = %q, the same as a corner", got)
+	}
+}
+
+func TestMenaceLearn(t *testing.T) {
+	rules := game.Standard()
+	m := NewMenace("", 1)
+	// X wins down the first column
+	moves := gametest.Moves(t, "a1", "b1", "a2", "b2", "a3")
+	if err := m.Learn(rules, moves, game.X); err != nil {
+		t.Fatal(err)
+	}
+	boxes, err := m.Boxes(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if boxes.Games != 1 {
+		t.Errorf("Games = %d, want 1", boxes.Games)
+	}
+
+	// X's opening gained beads
+	b := boxes.box(game.NewState(rules))
+	for i, cell := range b.cells {
+		want := initialBeads
+		if cell == 0 {
+			want += winBeads
+		}
+		if b.beads[i] != want {
+			t.Errorf("cell %d has %d beads, want %d", cell, b.beads[i], want)
+		}
+	}
+	// O's reply lost one, also found after the opposite corner, where it
+	// is b3 or c2 as the position is symmetric
+	b = boxes.box(gametest.Position(t, rules, "c3"))
+	var lost []int
+	for i, cell := range b.cells {
+		if b.beads[i] != initialBeads {
+			lost = append(lost, cell)
+		}
+	}
+	if len(lost) != 1 || lost[0] != 5 && lost[0] != 7 {
+		t.Errorf("cells %v changed after c3, want b3 or c2 alone", lost)
+	}
+
+	if err := m.Learn(rules, gametest.Moves(t, "a1", "a1"), game.Empty); err == nil {
+		t.Errorf("Learn() of a repeated move returned no error")
+	}
+}
+
+func TestMenaceSave(t *testing.T) {
+	dir := t.TempDir()
+	rules := game.Standard()
+	m := NewMenace(dir, 1)
+	if err := m.Learn(rules, gametest.Moves(t, "b2", "a1", "c1", "a3", "a2", "c2", "b1", "b3", "c3"), game.Empty); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	boxes, err := NewMenace(dir, 2).Boxes(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if boxes.Games != 1 || len(boxes.Beads) != 9 {
+		t.Errorf("loaded %d games and %d boxes, want 1 and 9", boxes.Games, len(boxes.Beads))
+	}
+
+	// Boxes kept by another version are forgotten
+	if err := os.WriteFile(m.Path(rules), []byte(`{"version":1,"games":5,"beads":{}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	boxes, err = NewMenace(dir, 3).Boxes(rules)
+	if err != nil || boxes.Games != 0 {
+		t.Errorf("Boxes() of an old version = %+v, %v, want none", boxes, err)
+	}
+}
@@ -0,0 +1,258 @@
+package ai
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+const (
+	// winScore is the value of a won position; it is reduced by the number
+	// of plies played so quicker wins and slower losses are preferred.
+	winScore = 1_000_000
+	// infinity bounds every possible score.
+	infinity = 2 * winScore
+	// neighbourhood limits the candidate moves on large boards to cells
+	// this close to a placed marker.
+	neighbourhood = 2
+	// wideBoard is the smallest board where candidates are limited.
+	wideBoard = 6
+	// checkEvery is how many nodes are searched between clock checks.
+	checkEvery = 1024
+)
+
+// Negamax is an alpha-beta search with iterative deepening. Positions cut
+// off by the depth limit are scored by counting open lines.
+type Negamax struct {
+	// MaxDepth limits the search in plies; zero searches to the end.
+	MaxDepth int
+	// Budget limits the thinking time; zero means no limit.
+	Budget time.Duration
+	// Blunder is the probability of playing a random move instead.
+	Blunder float64
+
+	rng  *rand.Rand
+	name string
+}
+
+// NewNegamax returns a search with the given limits.
+func NewNegamax(maxDepth int, budget time.Duration, seed int64) *Negamax {
+	return &Negamax{MaxDepth: maxDepth, Budget: budget, rng: rand.New(rand.NewSource(seed)), name: "Negamax"}
+}
+
+func (n *Negamax) Name() string {
+	return n.name
+}
+
+func (n *Negamax) Move(s *game.State) (game.Move, error) {
+	if s.IsTerminal() {
+		return game.Move{}, game.ErrGameOver
+	}
+	if n.rng.Float64() < n.Blunder {
+		return randomMove(s, n.rng)
+	}
+
+	sr := &search{pos: s.Clone()}
+	if n.Budget > 0 {
+		sr.deadline = time.Now().Add(n.Budget)
+	}
+
+	// Shuffle before ordering so equally good moves are picked at random
+	moves := candidates(sr.pos)
+	n.rng.Shuffle(len(moves), func(i, j int) { moves[i], moves[j] = moves[j], moves[i] })
+	orderMoves(sr.pos, moves)
+
+	best := moves[0]
+	for depth := 1; n.MaxDepth == 0 || depth <= n.MaxDepth; depth++ {
+		sr.cutoff = false
+		move, ok := sr.root(moves, depth)
+		if !ok {
+			// Out of time, keep the result of the last finished depth
+			break
+		}
+		best = move
+		if !sr.cutoff {
+			// Every line reached the end of the game, deeper is the same
+			break
+		}
+		// Search the best move first next time for better pruning
+		moveToFront(moves, best)
+	}
+	return best, nil
+}
+
+type search struct {
+	pos      *game.State
+	deadline time.Time
+	nodes    int
+	cutoff   bool
+	timeout  bool
+}
+
+// root returns the best move at the given depth, or false if time ran out.
+func (sr *search) root(moves []game.Move, depth int) (game.Move, bool) {
+	best, alpha := moves[0], -infinity
+	for _, m := range moves {
+		_ = sr.pos.Apply(m)
+		score := -sr.negamax(depth-1, -infinity, -alpha)
+		sr.pos.Undo()
+		if sr.timeout {
+			return best, false
+		}
+		if score > alpha {
+			best, alpha = m, score
+		}
+	}
+	return best, true
+}
+
+// negamax scores the position for the side to move.
+func (sr *search) negamax(depth, alpha, beta int) int {
+	if winner := sr.pos.Winner(); winner != game.Empty {
+		// The previous move decided the game, a loss for its side under
+		// misère rules
+		score := winScore - sr.pos.Plies()
+		if winner != sr.pos.Turn() {
+			score = -score
+		}
+		return score
+	}
+	if sr.pos.IsDraw() {
+		return 0
+	}
+	if depth == 0 {
+		sr.cutoff = true
+		return evaluate(sr.pos)
+	}
+	sr.nodes++
+	if sr.nodes%checkEvery == 0 && !sr.deadline.IsZero() && time.Now().After(sr.deadline) {
+		sr.timeout = true
+	}
+	if sr.timeout {
+		return 0
+	}
+
+	moves := candidates(sr.pos)
+	orderMoves(sr.pos, moves)
+	best := -infinity
+	for _, m := range moves {
+		_ = sr.pos.Apply(m)
+		score := -sr.negamax(depth-1, -beta, -alpha)
+		sr.pos.Undo()
+		best = max(best, score)
+		alpha = max(alpha, score)
+		if alpha >= beta {
+			break
+		}
+	}
+	return best
+}
+
+// candidates lists the moves worth searching. On wide boards only cells near
+// existing markers are considered, since distant moves are almost never best.
+// Misère rules reward keeping away, so there every cell counts.
+func candidates(s *game.State) []game.Move {
+	moves := s.LegalMoves()
+	size := s.Size()
+	if size < wideBoard || s.Rules().Misere {
+		return moves
+	}
+	if s.Plies() == 0 {
+		return []game.Move{{Row: size / 2, Col: size / 2}}
+	}
+	near := moves[:0]
+	for _, m := range moves {
+		if hasNeighbour(s, m) {
+			near = append(near, m)
+		}
+	}
+	return near
+}
+
+func hasNeighbour(s *game.State, m game.Move) bool {
+	for dr := -neighbourhood; dr <= neighbourhood; dr++ {
+		for dc := -neighbourhood; dc <= neighbourhood; dc++ {
+			p := game.Move{Row: m.Row + dr, Col: m.Col + dc}
+			if s.InBounds(p) && s.At(p.Row, p.Col) != game.Empty {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// orderMoves puts central cells first, they take part in the most lines.
+func orderMoves(s *game.State, moves []game.Move) {
+	centre := s.Size() - 1
+	distance := func(m game.Move) int {
+		return abs(2*m.Row-centre) + abs(2*m.Col-centre)
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return distance(moves[i]) < distance(moves[j])
+	})
+}
+
+func moveToFront(moves []game.Move, m game.Move) {
+	for i := range moves {
+		if moves[i] == m {
+			copy(moves[1:i+1], moves[:i])
+			moves[0] = m
+			return
+		}
+	}
+}
+
+// evaluate scores a non-terminal position for the side to move. Every run of
+// K cells holding markers of only one side counts for that side, weighted
+// heavily by how many markers it already holds.
+func evaluate(s *game.State) int {
+	size, k := s.Size(), s.Rules().K
+	score := 0
+	for _, d := range []game.Move{{Row: 0, Col: 1}, {Row: 1, Col: 0}, {Row: 1, Col: 1}, {Row: 1, Col: -1}} {
+		for row := 0; row < size; row++ {
+			for col := 0; col < size; col++ {
+				end := game.Move{Row: row + d.Row*(k-1), Col: col + d.Col*(k-1)}
+				if !s.InBounds(end) {
+					continue
+				}
+				var x, o int
+				for i := 0; i < k; i++ {
+					switch s.At(row+d.Row*i, col+d.Col*i) {
+					case game.X:
+						x++
+					case game.O:
+						o++
+					}
+				}
+				if x > 0 && o == 0 {
+					score += weight(x)
+				} else if o > 0 && x == 0 {
+					score -= weight(o)
+				}
+			}
+		}
+	}
+	if s.Rules().Misere {
+		// Lines to complete are threats to oneself
+		score = -score
+	}
+	return score * int(s.Turn())
+}
+
+// weight grows tenfold with every marker in an open run.
+func weight(markers int) int {
+	w := 1
+	for i := 0; i < markers; i++ {
+		w *= 10
+	}
+	return w
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
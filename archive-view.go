@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/archive"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/notation"
+)
+
+// archiveRows bounds the games listed at once.
+const archiveRows = 12
+
+// ArchiveModel lists the games recorded on this machine, newest first.
+type ArchiveModel struct {
+	width   int
+	height  int
+	records []notation.Record
+	filter  archive.Filter
+	// matches indexes the records passing the filter, newest first
+	matches      []int
+	cursor       int
+	errorMessage string
+}
+
+func NewArchiveModel(width, height int) ArchiveModel {
+	m := ArchiveModel{width: width, height: height}
+	records, bad, err := archive.Load()
+	switch {
+	case err != nil:
+		m.errorMessage = formatErrorMessage(err.Error())
+	case len(bad) > 0:
+		m.errorMessage = formatErrorMessage(fmt.Sprintf("Damaged games skipped: %d, the history command lists them", len(bad)))
+	}
+	m.records = records
+	m.applyFilter()
+	return m
+}
+
+func (m ArchiveModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ArchiveModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case constants.Up:
+			m.cursor = max(m.cursor-1, 0)
+		case constants.Down:
+			m.cursor = min(m.cursor+1, max(len(m.matches)-1, 0))
+		case constants.ModeFilter:
+			m.filter.Mode = nextChoice(archive.Modes, m.filter.Mode)
+			m.applyFilter()
+		case constants.ResultFilter:
+			m.filter.Result = nextChoice(archive.Results, m.filter.Result)
+			m.applyFilter()
+		case constants.Enter:
+			if len(m.matches) == 0 {
+				return m, nil
+			}
+			replay, err := NewReplayModel(m.width, m.height, m.records[m.matches[m.cursor]])
+			if err != nil {
+				m.errorMessage = formatErrorMessage(err.Error())
+				return m, nil
+			}
+			back := m
+			return replay.withBack(func(width, height int) (tea.Model, tea.Cmd) {
+				back.width, back.height = width, height
+				return back, nil
+			}), nil
+		case constants.M:
+			return initialModel(m.width, m.height), nil
+		case constants.Quit, constants.CtrlC, constants.Esc:
+			return m, tea.Quit
+		}
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	}
+	return m, nil
+}
+
+// applyFilter lists the matching games again, the newest selected.
+func (m *ArchiveModel) applyFilter() {
+	matches := m.filter.Apply(m.records)
+	m.matches = make([]int, 0, len(matches))
+	for i := len(matches) - 1; i >= 0; i-- {
+		m.matches = append(m.matches, matches[i])
+	}
+	m.cursor = 0
+}
+
+// nextChoice cycles through the choices, the empty value standing for all.
+func nextChoice[T comparable](choices []T, current T) T {
+	var all T
+	if current == all {
+		return choices[0]
+	}
+	for i, c := range choices {
+		if c == current && i+1 < len(choices) {
+			return choices[i+1]
+		}
+	}
+	return all
+}
+
+// archiveColumns describes a recorded game in a row of the game list.
+func archiveColumns(rec notation.Record) []string {
+	started := "-"
+	if !rec.Started.IsZero() {
+		started = rec.Started.Local().Format("2006-01-02 15:04")
+	}
+	mode := rec.Mode
+	if mode == "" {
+		mode = "-"
+	}
+	players := fmt.Sprintf("%s vs %s", playerName(rec, game.X, "X"), playerName(rec, game.O, "O"))
+	duration := "-"
+	if d := rec.Duration(); d > 0 {
+		duration = d.Round(time.Second).String()
+	}
+	return []string{started, mode, rec.Rules.String(), players, describeResult(rec), fmt.Sprintf("%d moves", len(rec.Moves)), duration}
+}
+
+// printArchive writes the matching games as a table, newest first, numbered
+// by their place in the archive.
+func printArchive(w io.Writer, records []notation.Record, filter archive.Filter) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tSTARTED\tMODE\tRULES\tPLAYERS\tRESULT\tMOVES\tDURATION")
+	matches := filter.Apply(records)
+	for i := len(matches) - 1; i >= 0; i-- {
+		columns := archiveColumns(records[matches[i]])
+		fmt.Fprintf(tw, "%d\t%s\n", matches[i]+1, strings.Join(columns, "\t"))
+	}
+	return tw.Flush()
+}
+
+func (m ArchiveModel) View() string {
+	header := constants.HeaderStyle.Render("Past games\n")
+
+	mode, result := "all", "all"
+	if m.filter.Mode != "" {
+		mode = m.filter.Mode
+	}
+	if m.filter.Result != "" {
+		result = string(m.filter.Result)
+	}
+	info := constants.InfoStyle.Render(fmt.Sprintf("%d of %d games | mode: %s | result: %s", len(m.matches), len(m.records), mode, result))
+
+	// The same width for every row keeps the columns aligned
+	rows := make([][]string, len(m.matches))
+	widths := make([]int, 7)
+	for i, index := range m.matches {
+		rows[i] = archiveColumns(m.records[index])
+		for c, column := range rows[i] {
+			widths[c] = max(widths[c], lipgloss.Width(column))
+		}
+	}
+
+	lines := []string{}
+	first := clamp(m.cursor-archiveRows/2, 0, max(len(rows)-archiveRows, 0))
+	for i := first; i < min(first+archiveRows, len(rows)); i++ {
+		columns := make([]string, len(rows[i]))
+		for c, column := range rows[i] {
+			columns[c] = column + strings.Repeat(" ", widths[c]-lipgloss.Width(column))
+		}
+		line := strings.Join(columns, "  ")
+		if i == m.cursor {
+			lines = append(lines, constants.SelectedStyle.Render("> "+line))
+		} else {
+			lines = append(lines, constants.NormalStyle.Render("  "+line))
+		}
+	}
+	if len(rows) == 0 {
+		lines = append(lines, constants.BlurredStyle.Render("No games recorded yet."))
+	}
+
+	errorMsg := ""
+	if m.errorMessage != "" {
+		errorMsg = constants.TCPErrStyle.Render(m.errorMessage)
+	}
+
+	footer := constants.SubtleStyle.Render("↑/↓: select | enter: replay | f: mode | r: result | m: menu | q, esc: quit")
+
+	view := lipgloss.JoinVertical(lipgloss.Center,
+		header,
+		info,
+		lipgloss.JoinVertical(lipgloss.Left, lines...),
+		errorMsg,
+		footer,
+	)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, view)
+}
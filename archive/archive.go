@@ -0,0 +1,154 @@
+// Package archive keeps every finished game in one notation file under the
+// XDG data directory, $XDG_DATA_HOME/tictactoe/games.ttt, which defaults to
+// ~/.local/share/tictactoe/games.ttt.
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/notation"
+)
+
+// How a game was played, stored in the Mode tag.
+const (
+	ModeHotSeat  = "hot-seat"
+	ModeComputer = "computer"
+	ModeDirect   = "direct"
+	ModeLobby    = "lobby"
+)
+
+// Modes lists every mode, in the order filters cycle through them.
+var Modes = []string{ModeHotSeat, ModeComputer, ModeDirect, ModeLobby}
+
+// Results lists every result of a finished game, in the order filters cycle
+// through them.
+var Results = []notation.Result{notation.XWins, notation.OWins, notation.Draw}
+
+// Dir returns the directory of the archive.
+func Dir() (string, error) {
+	// Relative paths are invalid per the XDG spec and must be ignored
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "tictactoe"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "tictactoe"), nil
+}
+
+// Path returns the file holding the games.
+func Path() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "games.ttt"), nil
+}
+
+// Append adds a game to the end of the archive, creating it when needed.
+func Append(rec notation.Record) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// One write per game keeps games whole when several programs append
+	var b bytes.Buffer
+	b.WriteString("\n")
+	if err := notation.Write(&b, rec); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load returns every archived game, oldest first. A missing archive holds no
+// games. Games that cannot be read are left out and reported in bad.
+func Load() (records []notation.Record, bad []error, err error) {
+	path, err := Path()
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	records, bad, err = notation.ReadAll(f)
+	for i, e := range bad {
+		bad[i] = fmt.Errorf("%s: %w", path, e)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return records, bad, nil
+}
+
+// Filter selects games by mode and result, empty fields match every game.
+type Filter struct {
+	Mode   string
+	Result notation.Result
+}
+
+// ParseFilter reads a mode and a result given as x, o or draw.
+func ParseFilter(mode, result string) (Filter, error) {
+	var f Filter
+	if mode != "" {
+		for _, m := range Modes {
+			if strings.EqualFold(mode, m) {
+				f.Mode = m
+			}
+		}
+		if f.Mode == "" {
+			return f, fmt.Errorf("unknown mode %q, want one of %s", mode, strings.Join(Modes, ", "))
+		}
+	}
+	switch strings.ToLower(result) {
+	case "":
+	case "x", string(notation.XWins):
+		f.Result = notation.XWins
+	case "o", string(notation.OWins):
+		f.Result = notation.OWins
+	case "draw", string(notation.Draw):
+		f.Result = notation.Draw
+	default:
+		return f, fmt.Errorf("unknown result %q, want x, o or draw", result)
+	}
+	return f, nil
+}
+
+// Match reports whether rec passes the filter.
+func (f Filter) Match(rec notation.Record) bool {
+	return (f.Mode == "" || rec.Mode == f.Mode) && (f.Result == "" || rec.Result == f.Result)
+}
+
+// Apply returns the indexes of the games that pass the filter.
+func (f Filter) Apply(records []notation.Record) []int {
+	var matches []int
+	for i, rec := range records {
+		if f.Match(rec) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,100 @@
+package archive
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game/gametest"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/notation"
+)
+
+func TestAppendLoad(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	if records, bad, err := Load(); records != nil || bad != nil || err != nil {
+		t.Fatalf("Load() of a missing archive = %v, %v, %v", records, bad, err)
+	}
+
+	want := []notation.Record{
+		{Rules: game.Standard(), Moves: gametest.Moves(t, "a1", "b1", "a2", "b2", "a3"), Result: notation.XWins, Mode: ModeHotSeat},
+		{Rules: game.Rules{Size: 4, K: 3}, Moves: gametest.Moves(t, "b2"), Result: notation.OWins, Termination: "resignation", Mode: ModeLobby},
+	}
+	for _, rec := range want {
+		if err := Append(rec); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	got, bad, err := Load()
+	if err != nil || len(bad) > 0 {
+		t.Fatalf("Load() error = %v, bad = %v", err, bad)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadSkipsDamaged(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	rec := notation.Record{Rules: game.Standard(), Moves: gametest.Moves(t, "b2"), Result: notation.Ongoing}
+	if err := Append(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	// A game cut short by a crash, followed by one appended later
+	path, err := Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("\n[Variant \"Classic\"]\n[Size \"3\"]\n[K \"3\"]\n\n1. b2 b2"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if err := Append(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	got, bad, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(got) != 2 || len(bad) != 1 {
+		t.Fatalf("Load() = %d games, bad = %v, want 2 games and 1 skipped", len(got), bad)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	records := []notation.Record{
+		{Result: notation.XWins, Mode: ModeHotSeat},
+		{Result: notation.Draw, Mode: ModeComputer},
+		{Result: notation.XWins, Mode: ModeComputer},
+	}
+	tests := []struct {
+		mode, result string
+		want         []int
+	}{
+		{"", "", []int{0, 1, 2}},
+		{"computer", "", []int{1, 2}},
+		{"", "x", []int{0, 2}},
+		{"Computer", "1-0", []int{2}},
+		{"lobby", "", nil},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.mode, tt.result)
+		if err != nil {
+			t.Fatalf("ParseFilter(%q, %q) error = %v", tt.mode, tt.result, err)
+		}
+		if got := f.Apply(records); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFilter(%q, %q).Apply() = %v, want %v", tt.mode, tt.result, got, tt.want)
+		}
+	}
+	for _, bad := range [][2]string{{"online", ""}, {"", "win"}} {
+		if _, err := ParseFilter(bad[0], bad[1]); err == nil {
+			t.Errorf("ParseFilter(%q, %q) accepted", bad[0], bad[1])
+		}
+	}
+}
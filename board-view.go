@@ -0,0 +1,105 @@
+package main
+
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// renderGrid lays out a size x size board, asking cell for the content of
+// every square. Boards above constants.CompactBoardSize use smaller cells.
+func renderGrid(size int, cell func(row, col int) string) string {
+	base := constants.CellStyle
+	if size > constants.CompactBoardSize {
+		base = constants.CompactCellStyle
+	}
+	return renderGridWith(size, base, cell)
+}
+
+// renderGridWith lays out a board using base as the style of every cell.
+func renderGridWith(size int, base lipgloss.Style, cell func(row, col int) string) string {
+	rows := make([]string, 0, size)
+	for row := 0; row < size; row++ {
+		cells := make([]string, 0, size)
+		for col := 0; col < size; col++ {
+			// Only draw separators between cells, the board style draws the outline
+			style := base.Border(constants.GridBorder, row > 0, false, false, col > 0)
+			cells = append(cells, style.Render(cell(row, col)))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, cells...))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
+
+// markerCell shows the placed markers, with marker blinking under the cursor.
+func markerCell(state game.Game, cursorRow, cursorCol int, marker string) func(row, col int) string {
+	return func(row, col int) string {
+		if row == cursorRow && col == cursorCol {
+			return constants.BlinkingStyle.Render(marker)
+		}
+		return state.At(row, col).String()
+	}
+}
+
+// highlightCell shows the placed markers with the winning line and, failing
+// that, the last move highlighted.
+func highlightCell(s *game.State) func(row, col int) string {
+	last, played := s.LastMove()
+	line := make(map[game.Move]bool)
+	for _, m := range s.WinningLine() {
+		line[m] = true
+	}
+	return func(row, col int) string {
+		m, marker := game.Move{Row: row, Col: col}, s.At(row, col).String()
+		switch {
+		case line[m]:
+			return constants.WinningCellStyle.Render(marker)
+		case played && m == last:
+			return constants.FocusedStyle.Render(marker)
+		}
+		return marker
+	}
+}
+
+// renderPosition draws any variant with the cursor marker blinking in the
+// selected cell.
+func renderPosition(g game.Game, cursorRow, cursorCol int, marker string) string {
+	switch g := g.(type) {
+	case *game.State:
+		return renderGrid(g.Size(), markerCell(g, cursorRow, cursorCol, marker))
+	case *game.UltimateState:
+		return renderUltimate(g, cursorRow, cursorCol, marker)
+	}
+	return ""
+}
+
+// renderUltimate nests the small boards inside the big one. Boards the next
+// move may go to are highlighted, claimed boards show only their winner.
+func renderUltimate(s *game.UltimateState, cursorRow, cursorCol int, marker string) string {
+	rows := make([]string, 0, 3)
+	for boardRow := 0; boardRow < 3; boardRow++ {
+		boards := make([]string, 0, 3)
+		for boardCol := 0; boardCol < 3; boardCol++ {
+			winner := s.BoardWinner(boardRow, boardCol)
+			grid := renderGridWith(3, constants.CompactCellStyle, func(row, col int) string {
+				row, col = boardRow*3+row, boardCol*3+col
+				if row == cursorRow && col == cursorCol {
+					return constants.BlinkingStyle.Render(marker)
+				}
+				if winner != game.Empty {
+					return constants.BlurredStyle.Render(winner.String())
+				}
+				return s.At(row, col).String()
+			})
+
+			style := constants.SubBoardStyle
+			if s.Playable(boardRow, boardCol) {
+				style = constants.PlayableBoardStyle
+			}
+			boards = append(boards, style.Render(grid))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, boards...))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/protocol"
+)
+
+const (
+	chatWidth  = 32
+	chatHeight = 12
+)
+
+// emotes are typed as short codes and shown as pictures on both ends.
+var emotes = strings.NewReplacer(
+	":)", "🙂",
+	":(", "🙁",
+	":D", "😄",
+	";)", "😉",
+	":P", "😛",
+	"<3", "❤️",
+	":gg:", "🤝",
+	":wave:", "👋",
+	":think:", "🤔",
+	":fire:", "🔥",
+	":cry:", "😢",
+)
+
+// chatPanel is the chat beside a networked board. While it is open it takes
+// the keyboard: the arrows scroll the history instead of moving the board's
+// cursor. Messages arriving while it is closed are counted as unread.
+type chatPanel struct {
+	input    textinput.Model
+	viewport viewport.Model
+	lines    []string
+	open     bool
+	unread   int
+}
+
+func newChatPanel() chatPanel {
+	input := textinput.New()
+	input.Placeholder = "Say something, :) :gg: :wave:"
+	input.CharLimit = protocol.MaxChatLength
+	input.Width = chatWidth - 4
+	input.Cursor.Style = constants.FocusedStyle
+
+	vp := viewport.New(chatWidth, chatHeight)
+	vp.SetContent(constants.BlurredStyle.Render("No messages yet."))
+	return chatPanel{input: input, viewport: vp}
+}
+
+// toggle opens the panel and gives it the keyboard, or closes it.
+func (c chatPanel) toggle() (chatPanel, tea.Cmd) {
+	c.open = !c.open
+	if !c.open {
+		c.input.Blur()
+		return c, nil
+	}
+	c.unread = 0
+	return c, c.input.Focus()
+}
+
+// add appends a message to the history and scrolls to it.
+func (c chatPanel) add(from, text string) chatPanel {
+	line := fmt.Sprintf("%s %s", from, emotes.Replace(printableOnly(text)))
+	c.lines = append(c.lines, lipgloss.NewStyle().Width(chatWidth).Render(line))
+	c.viewport.SetContent(strings.Join(c.lines, "\n"))
+	c.viewport.GotoBottom()
+	if !c.open {
+		c.unread++
+	}
+	return c
+}
+
+// printableOnly drops control characters, which would move the cursor or
+// restyle the terminal, and the peer refuses them anyway.
+func printableOnly(text string) string {
+	return strings.Map(func(r rune) rune {
+		if !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// update handles a key while the panel is open. It returns the text to send
+// when Enter was pressed.
+func (c chatPanel) update(msg tea.KeyMsg) (chatPanel, tea.Cmd, string) {
+	switch msg.String() {
+	case constants.Enter:
+		text := strings.TrimSpace(printableOnly(c.input.Value()))
+		c.input.Reset()
+		return c, nil, text
+	case constants.Up, constants.Down, "pgup", "pgdown":
+		var cmd tea.Cmd
+		c.viewport, cmd = c.viewport.Update(msg)
+		return c, cmd, ""
+	}
+	var cmd tea.Cmd
+	c.input, cmd = c.input.Update(msg)
+	return c, cmd, ""
+}
+
+// label is the footer entry for the chat, with the unread count.
+func (c chatPanel) label() string {
+	if c.open {
+		return "tab/esc: back to board | ↑/↓: scroll | enter: send"
+	}
+	if c.unread > 0 {
+		return fmt.Sprintf("tab: chat (%d unread)", c.unread)
+	}
+	return "tab: chat"
+}
+
+func (c chatPanel) View() string {
+	title := constants.FocusedStyle.Render("Chat")
+	return constants.ChatPanelStyle.Render(lipgloss.JoinVertical(lipgloss.Left,
+		title,
+		c.viewport.View(),
+		c.input.View(),
+	))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/clock"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// clockInterval is how often running clocks are redrawn and checked.
+const clockInterval = 100 * time.Millisecond
+
+// clockTickMsg remembers its clock, ticks of a clock that has since been
+// replaced, by a rematch for example, are dropped.
+type clockTickMsg struct {
+	clock *clock.Clock
+	at    time.Time
+}
+
+// tickClock schedules the next check of c, nothing for untimed games.
+func tickClock(c *clock.Clock) tea.Cmd {
+	if c == nil {
+		return nil
+	}
+	return tea.Tick(clockInterval, func(t time.Time) tea.Msg {
+		return clockTickMsg{clock: c, at: t}
+	})
+}
+
+// renderClocks shows the time left for both sides, the running clock
+// highlighted.
+func renderClocks(c *clock.Clock) string {
+	if c == nil {
+		return ""
+	}
+	lines := make([]string, 0, 2)
+	for _, p := range []game.Player{game.X, game.O} {
+		style := constants.BlurredStyle
+		if p == c.Running() {
+			style = constants.FocusedStyle
+		}
+		if p == c.Flagged() {
+			style = constants.ErrorStyle
+		}
+		lines = append(lines, style.Render(fmt.Sprintf("%s  %s", p, formatClock(c.Left(p)))))
+	}
+	return constants.ClockStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+// formatClock shows minutes and seconds, and tenths in the last ten seconds.
+func formatClock(d time.Duration) string {
+	if d < 10*time.Second {
+		return fmt.Sprintf("0:%04.1f", d.Seconds())
+	}
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// flagMessage announces who ran out of time.
+func flagMessage(flagged game.Player) string {
+	return fmt.Sprintf("%s ran out of time, %s wins!", flagged, flagged.Opponent())
+}
@@ -0,0 +1,176 @@
+// Package clock keeps chess-clock style time for the two sides of a game.
+package clock
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// Control is a time control. A side flags, losing the game, when its bank
+// runs out or a single move takes longer than PerMove. Zero fields are not
+// enforced, the zero Control means untimed games.
+type Control struct {
+	// Bank is the time each side has for the whole game
+	Bank time.Duration
+	// Increment is added to the bank after every move
+	Increment time.Duration
+	// PerMove limits a single move
+	PerMove time.Duration
+}
+
+// Enabled reports whether games are timed at all.
+func (c Control) Enabled() bool {
+	return c.Bank > 0 || c.PerMove > 0
+}
+
+func (c Control) String() string {
+	switch {
+	case !c.Enabled():
+		return "untimed"
+	case c.Bank == 0:
+		return fmt.Sprintf("%s per move", c.PerMove)
+	}
+	s := c.Bank.String()
+	if c.Increment > 0 {
+		s += fmt.Sprintf(" + %s", c.Increment)
+	}
+	if c.PerMove > 0 {
+		s += fmt.Sprintf(", %s per move", c.PerMove)
+	}
+	return s
+}
+
+// Validate rejects negative durations and an increment without a bank.
+func (c Control) Validate() error {
+	if c.Bank < 0 || c.Increment < 0 || c.PerMove < 0 {
+		return fmt.Errorf("time control %s: durations cannot be negative", c)
+	}
+	if c.Increment > 0 && c.Bank == 0 {
+		return fmt.Errorf("time control: an increment needs a bank")
+	}
+	return nil
+}
+
+// Clock runs the time of the side to move. Callers pass the current time so
+// the clock never reads it itself.
+type Clock struct {
+	control Control
+	bank    [2]time.Duration
+	move    time.Duration
+	running game.Player
+	since   time.Time
+	flagged game.Player
+}
+
+// New returns a stopped clock with full banks, nil when c is untimed.
+func New(c Control) *Clock {
+	if !c.Enabled() {
+		return nil
+	}
+	return &Clock{control: c, bank: [2]time.Duration{c.Bank, c.Bank}, move: c.PerMove}
+}
+
+func index(p game.Player) int {
+	if p == game.O {
+		return 1
+	}
+	return 0
+}
+
+// Control returns the time control the clock was started with.
+func (c *Clock) Control() Control {
+	return c.control
+}
+
+// Start runs the clock of p from now.
+func (c *Clock) Start(p game.Player, now time.Time) {
+	c.running, c.since = p, now
+}
+
+// Stop pauses the running clock, for example while a dropped game waits.
+func (c *Clock) Stop(now time.Time) {
+	c.Check(now)
+	c.running = game.Empty
+}
+
+// Running returns the side whose time runs, game.Empty when stopped.
+func (c *Clock) Running() game.Player {
+	return c.running
+}
+
+// Press ends the running side's move: it gets the increment and the
+// opponent's time starts.
+func (c *Clock) Press(now time.Time) {
+	if c.running == game.Empty || c.Check(now) != game.Empty {
+		return
+	}
+	if c.control.Bank > 0 {
+		c.bank[index(c.running)] += c.control.Increment
+	}
+	c.move = c.control.PerMove
+	c.Start(c.running.Opponent(), now)
+}
+
+// Check charges the time since the last call to the running side and
+// returns the side that flagged, or game.Empty.
+func (c *Clock) Check(now time.Time) game.Player {
+	if c.flagged != game.Empty || c.running == game.Empty {
+		return c.flagged
+	}
+	elapsed := now.Sub(c.since)
+	c.since = now
+	i := index(c.running)
+	if c.control.Bank > 0 {
+		c.bank[i] -= elapsed
+	}
+	if c.control.PerMove > 0 {
+		c.move -= elapsed
+	}
+	if (c.control.Bank > 0 && c.bank[i] <= 0) || (c.control.PerMove > 0 && c.move <= 0) {
+		c.flagged = c.running
+		c.running = game.Empty
+	}
+	return c.flagged
+}
+
+// Flagged returns the side that ran out of time, or game.Empty.
+func (c *Clock) Flagged() game.Player {
+	return c.flagged
+}
+
+// Left is the time p has before flagging as of the last Check.
+func (c *Clock) Left(p game.Player) time.Duration {
+	left := c.bank[index(p)]
+	if c.control.PerMove > 0 {
+		move := c.control.PerMove
+		if p == c.running || p == c.flagged {
+			move = c.move
+		}
+		if c.control.Bank == 0 || move < left {
+			left = move
+		}
+	}
+	return max(left, 0)
+}
+
+// Banks returns both banks and the time left for the current move.
+func (c *Clock) Banks() (x, o, move time.Duration) {
+	return c.bank[0], c.bank[1], c.move
+}
+
+// Sync adopts the times of an authoritative clock, with running to move. A
+// flag seen by this clock alone is forgotten, the authority decides.
+func (c *Clock) Sync(x, o, move time.Duration, running game.Player, now time.Time) {
+	c.bank = [2]time.Duration{x, o}
+	c.move = move
+	c.flagged = game.Empty
+	c.Start(running, now)
+}
+
+// Flag records that p ran out of time, as decided by an authoritative clock.
+func (c *Clock) Flag(p game.Player) {
+	c.flagged = p
+	c.running = game.Empty
+}
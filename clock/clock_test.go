@@ -0,0 +1,185 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+var start = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// at is the time d after start.
+func at(d time.Duration) time.Time {
+	return start.Add(d)
+}
+
+func TestControl(t *testing.T) {
+	tests := []struct {
+		control Control
+		name    string
+		valid   bool
+	}{
+		{Control{}, "untimed", true},
+		{Control{Bank: time.Minute}, "1m0s", true},
+		{Control{Bank: time.Minute, Increment: 2 * time.Second}, "1m0s + 2s", true},
+		{Control{Bank: time.Minute, PerMove: 10 * time.Second}, "1m0s, 10s per move", true},
+		{Control{PerMove: 10 * time.Second}, "10s per move", true},
+		{Control{Increment: time.Second}, "untimed", false},
+		{Control{Bank: -time.Second}, "untimed", false},
+		{Control{Bank: time.Minute, PerMove: -time.Second}, "1m0s", false},
+	}
+	for _, tt := range tests {
+		if got := tt.control.String(); got != tt.name {
+			t.Errorf("%+v: String() = %q, want %q", tt.control, got, tt.name)
+		}
+		if err := tt.control.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v: Validate() error = %v, want valid %v", tt.control, err, tt.valid)
+		}
+	}
+	if New(Control{}) != nil {
+		t.Errorf("New() of an untimed control returned a clock")
+	}
+}
+
+func TestClock(t *testing.T) {
+	type step struct {
+		// press ends the move at the time, otherwise the clock is only checked
+		press bool
+		at    time.Duration
+	}
+	tests := []struct {
+		name    string
+		control Control
+		steps   []step
+		flagged game.Player
+		x, o    time.Duration
+	}{
+		{
+			"banks run down in turn",
+			Control{Bank: time.Minute},
+			[]step{{true, 10 * time.Second}, {true, 30 * time.Second}, {false, 35 * time.Second}},
+			game.Empty, 45 * time.Second, 40 * time.Second,
+		},
+		{
+			"increment after every move",
+			Control{Bank: time.Minute, Increment: 5 * time.Second},
+			[]step{{true, 10 * time.Second}, {true, 20 * time.Second}},
+			game.Empty, 55 * time.Second, 55 * time.Second,
+		},
+		{
+			"flag fall on the bank",
+			Control{Bank: time.Minute},
+			[]step{{true, 10 * time.Second}, {false, 70 * time.Second}},
+			game.O, 50 * time.Second, 0,
+		},
+		{
+			"no increment for a move made too late",
+			Control{Bank: time.Minute, Increment: 5 * time.Second},
+			[]step{{true, 61 * time.Second}},
+			game.X, 0, time.Minute,
+		},
+		{
+			"flag fall on the move limit",
+			Control{Bank: time.Minute, PerMove: 10 * time.Second},
+			[]step{{true, 5 * time.Second}, {false, 16 * time.Second}},
+			game.O, 10 * time.Second, 0,
+		},
+		{
+			"the move limit starts over",
+			Control{PerMove: 10 * time.Second},
+			[]step{{true, 9 * time.Second}, {true, 18 * time.Second}, {false, 27 * time.Second}},
+			game.Empty, 0, 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.control)
+			c.Start(game.X, start)
+			for _, s := range tt.steps {
+				if s.press {
+					c.Press(at(s.at))
+				} else {
+					c.Check(at(s.at))
+				}
+			}
+			if got := c.Flagged(); got != tt.flagged {
+				t.Errorf("Flagged() = %v, want %v", got, tt.flagged)
+			}
+			if tt.flagged != game.Empty && c.Running() != game.Empty {
+				t.Errorf("Running() = %v after the flag fell", c.Running())
+			}
+			if tt.control.Bank > 0 {
+				if got := c.Left(game.X); got != tt.x {
+					t.Errorf("Left(X) = %v, want %v", got, tt.x)
+				}
+				if got := c.Left(game.O); got != tt.o {
+					t.Errorf("Left(O) = %v, want %v", got, tt.o)
+				}
+			}
+		})
+	}
+}
+
+func TestLeftPerMove(t *testing.T) {
+	c := New(Control{Bank: 15 * time.Second, PerMove: 10 * time.Second})
+	c.Start(game.X, start)
+	c.Check(at(4 * time.Second))
+	if got := c.Left(game.X); got != 6*time.Second {
+		t.Errorf("Left(X) = %v, want the 6s left of the move", got)
+	}
+	if got := c.Left(game.O); got != 10*time.Second {
+		t.Errorf("Left(O) = %v, want a full move", got)
+	}
+
+	// Close to the end of the bank it is the bank that runs out first
+	c.Press(at(9 * time.Second))
+	c.Press(at(10 * time.Second))
+	c.Check(at(14 * time.Second))
+	if got := c.Left(game.X); got != 2*time.Second {
+		t.Errorf("Left(X) = %v, want the 2s left in the bank", got)
+	}
+	c.Check(at(16 * time.Second))
+	if got := c.Left(game.X); got != 0 || c.Flagged() != game.X {
+		t.Errorf("Left(X) = %v, flagged %v, want X out of time", got, c.Flagged())
+	}
+}
+
+func TestStop(t *testing.T) {
+	c := New(Control{Bank: time.Minute})
+	c.Start(game.X, start)
+	c.Stop(at(10 * time.Second))
+	// A stopped clock charges nobody
+	if c.Check(at(time.Hour)) != game.Empty || c.Left(game.X) != 50*time.Second {
+		t.Errorf("Left(X) = %v after waiting stopped, want 50s", c.Left(game.X))
+	}
+	c.Press(at(time.Hour))
+	if c.Running() != game.Empty {
+		t.Errorf("Press() started a stopped clock")
+	}
+}
+
+func TestSync(t *testing.T) {
+	c := New(Control{Bank: time.Minute, PerMove: 20 * time.Second})
+	c.Start(game.X, start)
+	c.Check(at(25 * time.Second))
+	if c.Flagged() != game.X {
+		t.Fatalf("Flagged() = %v, want X over the move limit", c.Flagged())
+	}
+
+	// The authority saw the move in time
+	c.Sync(40*time.Second, 50*time.Second, 15*time.Second, game.O, at(25*time.Second))
+	if c.Flagged() != game.Empty || c.Running() != game.O {
+		t.Errorf("after Sync(): flagged %v, running %v, want O to move", c.Flagged(), c.Running())
+	}
+	c.Check(at(30 * time.Second))
+	x, o, move := c.Banks()
+	if x != 40*time.Second || o != 45*time.Second || move != 10*time.Second {
+		t.Errorf("Banks() = %v, %v, %v, want 40s, 45s, 10s", x, o, move)
+	}
+
+	c.Flag(game.O)
+	if c.Flagged() != game.O || c.Check(at(time.Hour)) != game.O || c.Running() != game.Empty {
+		t.Errorf("Flag(O) did not stop the clock with O flagged")
+	}
+}
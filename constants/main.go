@@ -1,24 +1,49 @@
 package constants
 
 const (
-	Empty     = 0
-	PlayerX   = 1
-	PlayerO   = -1
-	BoardSize = 3
-	Down      = "down"
-	Left      = "left"
-	Right     = "right"
-	Quit      = "q"
-	Esc       = "esc"
-	M         = "m"
-	Tab       = "tab"
-	ShiftTab  = "shift+tab"
-	Enter     = "enter"
-	Up        = "up"
-	DownKey   = "down"
-	LeftKey   = "left"
-	RightKey  = "right"
-	EscKey    = "esc"
-	CtrlC     = "ctrl+c"
-	CtrlR     = "ctrl+r"
+	Empty    = 0
+	PlayerX  = 1
+	PlayerO  = -1
+	Down     = "down"
+	Left     = "left"
+	Right    = "right"
+	Quit     = "q"
+	Esc      = "esc"
+	M        = "m"
+	Tab      = "tab"
+	ShiftTab = "shift+tab"
+	Enter    = "enter"
+	Up       = "up"
+	DownKey  = "down"
+	LeftKey  = "left"
+	RightKey = "right"
+	EscKey   = "esc"
+	CtrlC    = "ctrl+c"
+	CtrlR    = "ctrl+r"
+	Resign   = "r"
+	Rematch  = "p"
+	Decline  = "d"
+	Create   = "c"
+	Auto     = "a"
+	JoinCode = "j"
+	Refresh  = "r"
+	Undo     = "u"
+	CtrlZ    = "ctrl+z"
+	Redo     = "U"
+	CtrlY    = "ctrl+y"
+	Home     = "home"
+	End      = "end"
+	Save     = "s"
+	Space    = " "
+	Faster   = "+"
+	Slower   = "-"
+	// the overlay showing how every empty cell scores
+	Hint     = "h"
+	Analysis = "a"
+	// the filters of the past games list
+	ModeFilter   = "f"
+	ResultFilter = "r"
 )
+
+// CompactBoardSize is the largest board drawn with full-size cells.
+const CompactBoardSize = 5
@@ -34,4 +34,26 @@ var (
 	FocusedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFD700"))
 	BlurredStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
 	NoStyle        = lipgloss.NewStyle()
+
+	// CompactCellStyle keeps boards larger than CompactBoardSize on screen
+	CompactCellStyle = CellStyle.Width(3).Height(1)
+	// SubBoardStyle frames the small boards of Ultimate Tic-Tac-Toe
+	SubBoardStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240"))
+	// PlayableBoardStyle highlights the small boards the next move may go to
+	PlayableBoardStyle = SubBoardStyle.BorderForeground(lipgloss.Color("#FFD700"))
+	// GridBorder draws the separators between cells, crossing where they meet
+	GridBorder = lipgloss.Border{Top: "─", Left: "│", TopLeft: "┼"}
+	// ChatPanelStyle frames the chat next to a networked board
+	ChatPanelStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("#FFD700")).Padding(0, 1).Margin(0, 0, 0, 2)
+	// ClockStyle frames the two clocks of a timed game
+	ClockStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240")).Padding(0, 1).Margin(0, 0, 0, 2)
+	// HistoryStyle frames the move list next to a local board
+	HistoryStyle = ClockStyle
+	// WinningCellStyle marks the line that won a replayed game
+	WinningCellStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#2ECC71"))
+	// HintWinStyle, HintDrawStyle and HintLossStyle color the empty cells by
+	// the outcome of playing there
+	HintWinStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#2ECC71"))
+	HintDrawStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#F1C40F"))
+	HintLossStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#E74C3C"))
 )
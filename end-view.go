@@ -6,12 +6,25 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
 )
 
+// winMessage announces the winner of a game, naming the player who completed
+// a line and lost under misère rules.
+func winMessage(rules game.Rules, winner game.Player) string {
+	if rules.Misere {
+		return fmt.Sprintf("Player %s completed a line and loses!", winner.Opponent())
+	}
+	return fmt.Sprintf("Player %s wins!", winner)
+}
+
 type EndGameModel struct {
 	width   int
 	height  int
 	message string
+	// series and rematch are set when the same players can play again
+	series  *series
+	rematch func(width, height int) (tea.Model, tea.Cmd)
 }
 
 func NewEndGameModel(width, height int, endGameMessage string) *EndGameModel {
@@ -22,6 +35,13 @@ func NewEndGameModel(width, height int, endGameMessage string) *EndGameModel {
 	}
 }
 
+// withRematch offers to play again, showing the score of the series so far.
+func (m *EndGameModel) withRematch(s *series, rematch func(width, height int) (tea.Model, tea.Cmd)) *EndGameModel {
+	m.series = s
+	m.rematch = rematch
+	return m
+}
+
 func (m *EndGameModel) Init() tea.Cmd {
 	return nil
 }
@@ -30,6 +50,10 @@ func (m *EndGameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
+		case constants.Rematch:
+			if m.rematch != nil {
+				return m.rematch(m.width, m.height)
+			}
 		case constants.M:
 			return initialModel(m.width, m.height), nil
 		case constants.Quit, constants.CtrlC, constants.Esc:
@@ -44,6 +68,9 @@ func (m *EndGameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m *EndGameModel) View() string {
 	message := fmt.Sprintf("%s\n\nPress 'm' to return to menu.", m.message)
+	if m.rematch != nil {
+		message = fmt.Sprintf("%s\n\n%s\n\nPress 'p' to play again, 'm' to return to menu.", m.message, m.series)
+	}
 	styledMessage := constants.HighlightStyle.Render(message)
 
 	centeredMessage := lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, styledMessage)
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/ai"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/engine"
+	"github.com/spf13/cobra"
+)
+
+// newEngineCmd plays through the engine protocol on stdin and stdout.
+func newEngineCmd() *cobra.Command {
+	var seed int64
+	cmd := &cobra.Command{
+		Use:   "engine",
+		Short: "Play through a text protocol on stdin and stdout",
+		Long:  "Read commands such as 'newgame size=3 k=3', 'play b2' and 'genmove' from stdin and answer on stdout, for bots and scripts. Run 'help' for every command.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ai.Seeded = cmd.Flags().Changed("seed")
+			if !ai.Seeded {
+				seed = time.Now().UnixNano()
+			}
+			return engine.New(menuRules(), seed).Run(os.Stdin, os.Stdout)
+		},
+	}
+	cmd.Flags().Int64Var(&seed, "seed", 0, "seed for the computer's random choices, random by default")
+	return cmd
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Client drives an engine running in another process, such as
+// "Tic-Tac-Toe engine" or a bot speaking the same protocol.
+type Client struct {
+	// Timeout bounds the wait for every answer, no limit when zero. An
+	// engine that takes longer is killed.
+	Timeout time.Duration
+
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+}
+
+// Start runs the engine command line and connects to its stdin and stdout.
+// Its stderr is passed through to ours.
+func Start(command string) (*Client, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, errors.New("empty engine command")
+	}
+	cmd := exec.Command(fields[0], fields[1:]...)
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &Client{cmd: cmd, in: in, out: bufio.NewReader(out)}, nil
+}
+
+// Command sends one command line and waits for the answer. A "?" answer
+// is returned as an error. Timeout covers the write as well, an engine that
+// stops reading fills the pipe and would block it.
+func (c *Client) Command(line string) (string, error) {
+	var expired atomic.Bool
+	if c.Timeout > 0 {
+		timer := time.AfterFunc(c.Timeout, func() {
+			expired.Store(true)
+			_ = c.cmd.Process.Kill()
+		})
+		defer timer.Stop()
+	}
+
+	if _, err := fmt.Fprintln(c.in, line); err != nil {
+		if expired.Load() {
+			return "", fmt.Errorf("%s: engine did not read it within %v", line, c.Timeout)
+		}
+		return "", err
+	}
+
+	var lines []string
+	for {
+		text, err := c.out.ReadString('\n')
+		if expired.Load() {
+			return "", fmt.Errorf("%s: engine did not answer within %v", line, c.Timeout)
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: engine stopped answering: %w", line, err)
+		}
+		text = strings.TrimRight(text, "\r\n")
+		if text == "" {
+			if len(lines) > 0 {
+				break
+			}
+			// Tolerate stray empty lines before the answer
+			continue
+		}
+		lines = append(lines, text)
+	}
+
+	status, answer := lines[0][0], strings.TrimSpace(strings.Join(append([]string{lines[0][1:]}, lines[1:]...), "\n"))
+	switch status {
+	case '=':
+		return answer, nil
+	case '?':
+		return "", fmt.Errorf("%s: %s", line, answer)
+	}
+	return "", fmt.Errorf("%s: malformed answer %q", line, lines[0])
+}
+
+// Close asks the engine to quit and waits for it to exit, killing it once
+// Timeout has passed.
+func (c *Client) Close() error {
+	if c.Timeout > 0 {
+		timer := time.AfterFunc(c.Timeout, func() { _ = c.cmd.Process.Kill() })
+		defer timer.Stop()
+	}
+	_, _ = fmt.Fprintln(c.in, "quit")
+	_ = c.in.Close()
+	return c.cmd.Wait()
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// helperEnv tells the test binary to act as the engine named by its value.
+const helperEnv = "ENGINE_TEST_HELPER"
+
+// TestHelperEngine is not a test: started by helper it plays an engine.
+func TestHelperEngine(t *testing.T) {
+	switch os.Getenv(helperEnv) {
+	case "":
+		return
+	case "engine":
+		_ = New(game.Standard(), 1).Run(os.Stdin, os.Stdout)
+	case "silent":
+		// Reads every command and never answers
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+		}
+	case "garbage":
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			fmt.Print("\nhello\n\n")
+		}
+	}
+	os.Exit(0)
+}
+
+// helper starts the test binary as an engine behaving like kind.
+func helper(t *testing.T, kind string) *Client {
+	t.Helper()
+	t.Setenv(helperEnv, kind)
+	c, err := Start(os.Args[0] + " -test.run=^TestHelperEngine$")
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	return c
+}
+
+func TestClient(t *testing.T) {
+	c := helper(t, "engine")
+	c.Timeout = 10 * time.Second
+
+	if answer, err := c.Command("play b2"); err != nil || answer != "" {
+		t.Fatalf("play b2 = %q, %v", answer, err)
+	}
+	_, err := c.Command("play b2")
+	if err == nil || !strings.Contains(err.Error(), game.ErrOccupied.Error()) {
+		t.Errorf("play b2 again error = %v, want the engine's refusal", err)
+	}
+	board, err := c.Command("board")
+	if want := "a b c\n 1 . . .\n 2 . X .\n 3 . . ."; err != nil || board != want {
+		t.Errorf("board = %q, %v, want %q", board, err, want)
+	}
+	if move, err := c.Command("genmove"); err != nil || len(move) != 2 {
+		t.Errorf("genmove = %q, %v, want a cell", move, err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	c := helper(t, "silent")
+	c.Timeout = 200 * time.Millisecond
+
+	start := time.Now()
+	_, err := c.Command("genmove")
+	if err == nil || !strings.Contains(err.Error(), "did not answer") {
+		t.Errorf("Command() error = %v, want a timeout", err)
+	}
+	if waited := time.Since(start); waited > 5*time.Second {
+		t.Errorf("Command() returned after %v, want about %v", waited, c.Timeout)
+	}
+	// The engine was killed, so closing does not wait either
+	_ = c.Close()
+}
+
+func TestClientMalformed(t *testing.T) {
+	c := helper(t, "garbage")
+	c.Timeout = 10 * time.Second
+	defer c.Close()
+
+	if _, err := c.Command("turn"); err == nil || !strings.Contains(err.Error(), "malformed answer") {
+		t.Errorf("Command() error = %v, want a malformed answer", err)
+	}
+}
+
+func TestStartRejects(t *testing.T) {
+	if _, err := Start("  "); err == nil {
+		t.Errorf("Start() of an empty command returned no error")
+	}
+	if _, err := Start("/nonexistent/engine"); err == nil {
+		t.Errorf("Start() of a missing program returned no error")
+	}
+}
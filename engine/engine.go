@@ -0,0 +1,280 @@
+// Package engine drives the rules and the computer opponents through a line
+// based text protocol, in the spirit of GTP and UCI, so scripts and other
+// programs can play without the TUI.
+//
+// Every line is one command with its arguments separated by spaces. Empty
+// lines and lines starting with # are ignored. Every answer starts with
+// "= " on success or "? " on failure and ends with an empty line, so answers
+// spanning several lines, like the board, are easy to read back:
+//
+//	newgame size=3 k=3
+//	=
+//
+//	play b2
+//	=
+//
+//	genmove
+//	= a1
+//
+//	play b2
+//	? b2: cell is already occupied
+//
+// The commands are:
+//
+//	newgame [variant=classic|ultimate] [size=N] [k=N] [misere=true]  start a new game
+//	play <cell>     play a cell, named like b2, for the side to move
+//	genmove         let the computer play for the side to move
+//	undo            take back the last move
+//	level <level>   set the computer to easy, medium, perfect, mcts or menace
+//	board           draw the board, one row per line
+//	legal           list the legal cells
+//	moves           list the cells played so far
+//	turn            name the side to move, X or O
+//	result          1-0, 0-1, 1/2-1/2 or * like the saved games
+//	help            list the commands
+//	quit            end the session
+package engine
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/ai"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/notation"
+)
+
+// DefaultLevel is the strength genmove plays at until level changes it.
+const DefaultLevel = ai.Perfect
+
+// ErrQuit is returned by Execute for the quit command.
+var ErrQuit = errors.New("quit")
+
+// Engine holds one game and the computer that plays genmove.
+type Engine struct {
+	game  game.Game
+	level ai.Difficulty
+	seed  int64
+	agent ai.Agent
+}
+
+// New starts an engine on a game under rules, which must be valid. The seed
+// makes the computer's random choices reproducible.
+func New(rules game.Rules, seed int64) *Engine {
+	return &Engine{
+		game:  game.New(rules),
+		level: DefaultLevel,
+		seed:  seed,
+		agent: ai.New(DefaultLevel, seed),
+	}
+}
+
+// Game returns the game being played.
+func (e *Engine) Game() game.Game {
+	return e.game
+}
+
+// Run answers the commands read from r on w until r ends or quit is read.
+func (e *Engine) Run(r io.Reader, w io.Writer) error {
+	out := bufio.NewWriter(w)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		answer, err := e.Execute(line)
+		if errors.Is(err, ErrQuit) {
+			fmt.Fprint(out, "=\n\n")
+			return out.Flush()
+		}
+		if err != nil {
+			fmt.Fprintf(out, "? %v\n\n", err)
+		} else if answer == "" || strings.HasPrefix(answer, "\n") {
+			fmt.Fprintf(out, "=%s\n\n", answer)
+		} else {
+			fmt.Fprintf(out, "= %s\n\n", answer)
+		}
+		// Whoever drives us waits for the answer before the next command
+		if err := out.Flush(); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Execute runs one command line and returns its answer.
+func (e *Engine) Execute(line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", errors.New("empty command")
+	}
+	name, args := strings.ToLower(fields[0]), fields[1:]
+
+	switch name {
+	case "newgame":
+		return "", e.newGame(args)
+	case "play":
+		if len(args) != 1 {
+			return "", errors.New("usage: play <cell>")
+		}
+		move, err := game.ParseMove(strings.ToLower(args[0]))
+		if err != nil {
+			return "", err
+		}
+		return "", e.play(move)
+	case "genmove":
+		move, err := e.genMove()
+		if err != nil {
+			return "", err
+		}
+		return move.String(), nil
+	case "undo":
+		if _, ok := e.game.Undo(); !ok {
+			return "", errors.New("no move to undo")
+		}
+		return "", nil
+	case "level":
+		if len(args) != 1 {
+			return "", errors.New("usage: level easy|medium|perfect|mcts|menace")
+		}
+		d, err := ai.ParseDifficulty(args[0])
+		if err != nil {
+			return "", err
+		}
+		if err := d.CheckRules(e.game.Rules()); err != nil {
+			return "", err
+		}
+		e.level, e.agent = d, ai.New(d, e.seed)
+		return "", nil
+	case "board":
+		return "\n" + Board(e.game), nil
+	case "legal":
+		return cells(e.game.LegalMoves()), nil
+	case "moves":
+		return cells(e.game.Moves()), nil
+	case "turn":
+		if e.game.IsTerminal() {
+			return "", game.ErrGameOver
+		}
+		return e.game.Turn().String(), nil
+	case "result":
+		return string(notation.New(e.game, "", "", time.Time{}).Result), nil
+	case "help":
+		return strings.Join(commands, " "), nil
+	case "quit":
+		return "", ErrQuit
+	}
+	return "", fmt.Errorf("unknown command %q, try help", fields[0])
+}
+
+var commands = []string{"newgame", "play", "genmove", "undo", "level", "board", "legal", "moves", "turn", "result", "help", "quit"}
+
+// newGame reads key=value arguments on top of the standard rules.
+func (e *Engine) newGame(args []string) error {
+	rules := game.Standard()
+	variantSet := false
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fmt.Errorf("bad argument %q: want key=value", arg)
+		}
+		switch strings.ToLower(key) {
+		case "variant":
+			v, err := game.ParseVariant(value)
+			if err != nil {
+				return err
+			}
+			rules.Variant, variantSet = v, true
+		case "misere":
+			misere, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("bad misere %q: want true or false", value)
+			}
+			rules.Misere = misere
+		case "size", "k":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("bad %s %q: want a number", key, value)
+			}
+			if strings.ToLower(key) == "size" {
+				rules.Size = n
+			} else {
+				rules.K = n
+			}
+		default:
+			return fmt.Errorf("unknown argument %q: want variant, size, k or misere", key)
+		}
+	}
+	if variantSet && rules.Variant == game.Ultimate {
+		misere := rules.Misere
+		rules = game.UltimateRules()
+		rules.Misere = misere
+	}
+	if err := rules.Validate(); err != nil {
+		return err
+	}
+	if err := e.level.CheckRules(rules); err != nil {
+		return fmt.Errorf("%w, choose another level first", err)
+	}
+	e.game = game.New(rules)
+	e.agent = ai.New(e.level, e.seed)
+	return nil
+}
+
+func (e *Engine) genMove() (game.Move, error) {
+	s, ok := e.game.(*game.State)
+	if !ok {
+		return game.Move{}, errors.New("the computer only plays the classic variant")
+	}
+	move, err := e.agent.Move(s)
+	if err != nil {
+		return game.Move{}, err
+	}
+	return move, e.play(move)
+}
+
+// play applies m, naming the cell rather than coordinates when it is refused.
+func (e *Engine) play(m game.Move) error {
+	var me *game.MoveError
+	err := e.game.Apply(m)
+	if errors.As(err, &me) {
+		return fmt.Errorf("%s: %w", m, me.Err)
+	}
+	return err
+}
+
+// Board draws g with column letters on top and row numbers on the left,
+// matching the cell names.
+func Board(g game.Game) string {
+	var b strings.Builder
+	b.WriteString("  ")
+	for col := 0; col < g.Size(); col++ {
+		fmt.Fprintf(&b, " %c", 'a'+col)
+	}
+	for row := 0; row < g.Size(); row++ {
+		fmt.Fprintf(&b, "\n%2d", row+1)
+		for col := 0; col < g.Size(); col++ {
+			marker := "."
+			if p := g.At(row, col); p != game.Empty {
+				marker = p.String()
+			}
+			b.WriteString(" " + marker)
+		}
+	}
+	return b.String()
+}
+
+func cells(moves []game.Move) string {
+	names := make([]string, len(moves))
+	for i, m := range moves {
+		names[i] = m.String()
+	}
+	return strings.Join(names, " ")
+}
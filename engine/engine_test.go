@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup runs first, every command must succeed
+		setup []string
+		line  string
+		want  string
+		// err is part of the error expected, empty for success
+		err string
+	}{
+		{"play", nil, "play b2", "", ""},
+		{"play in capitals", nil, "PLAY B2", "", ""},
+		{"moves", []string{"play b2", "play a1"}, "moves", "b2 a1", ""},
+		{"turn", []string{"play b2"}, "turn", "O", ""},
+		{"legal", []string{"newgame size=3 k=3", "play a1", "play b1", "play c1", "play a2", "play b2", "play c2", "play b3"}, "legal", "a3 c3", ""},
+		{"result of a won game", []string{"play a1", "play b1", "play a2", "play b2", "play a3"}, "result", "1-0", ""},
+		{"result of a misère game", []string{"newgame misere=true", "play a1", "play b1", "play a2", "play b2", "play a3"}, "result", "0-1", ""},
+		{"result so far", []string{"play a1"}, "result", "*", ""},
+		{"undo", []string{"play a1", "play b2", "undo"}, "moves", "a1", ""},
+		{"board", []string{"play b2", "play a3"}, "board", "\n   a b c\n 1 . . .\n 2 . X .\n 3 O . .", ""},
+		{"wide board", []string{"newgame size=5 k=4", "play e5"}, "moves", "e5", ""},
+		{"level", nil, "level easy", "", ""},
+
+		{"unknown command", nil, "dance", "", `unknown command "dance"`},
+		{"play without a cell", nil, "play", "", "usage: play"},
+		{"play a bad cell", nil, "play 2b", "", "bad cell"},
+		{"play an occupied cell", []string{"play b2"}, "play b2", "", "b2: " + game.ErrOccupied.Error()},
+		{"play off the board", nil, "play d1", "", "d1: " + game.ErrOutOfBounds.Error()},
+		{"play after the end", []string{"play a1", "play b1", "play a2", "play b2", "play a3"}, "play c3", "", game.ErrGameOver.Error()},
+		{"turn after the end", []string{"play a1", "play b1", "play a2", "play b2", "play a3"}, "turn", "", game.ErrGameOver.Error()},
+		{"undo on an empty board", nil, "undo", "", "no move to undo"},
+		{"unknown level", nil, "level godlike", "", "godlike"},
+		{"newgame without =", nil, "newgame size", "", "want key=value"},
+		{"newgame with a bad number", nil, "newgame size=big", "", `bad size "big"`},
+		{"newgame with an unknown key", nil, "newgame colour=red", "", `unknown argument "colour"`},
+		{"newgame with a bad misere", nil, "newgame misere=often", "", `bad misere "often"`},
+		{"misère ultimate", nil, "newgame variant=ultimate misere=true", "", "single board"},
+		{"newgame with bad rules", nil, "newgame size=3 k=4", "", "does not fit"},
+		{"menace on a large board", []string{"newgame size=4 k=3"}, "level menace", "", "MENACE only learns"},
+		{"large board for menace", []string{"level menace"}, "newgame size=4 k=3", "", "choose another level first"},
+		{"genmove on Ultimate", []string{"newgame variant=ultimate"}, "genmove", "", "only plays the classic variant"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(game.Standard(), 1)
+			for _, line := range tt.setup {
+				if _, err := e.Execute(line); err != nil {
+					t.Fatalf("%s: %v", line, err)
+				}
+			}
+			got, err := e.Execute(tt.line)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Execute(%q) error = %v, want one containing %q", tt.line, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute(%q) error = %v", tt.line, err)
+			}
+			if tt.want != "" && got != tt.want {
+				t.Errorf("Execute(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGame(t *testing.T) {
+	tests := []struct {
+		line string
+		want game.Rules
+	}{
+		{"newgame", game.Standard()},
+		{"newgame size=5 k=4", game.Rules{Size: 5, K: 4}},
+		{"newgame K=4 Size=6", game.Rules{Size: 6, K: 4}},
+		{"newgame variant=ultimate", game.UltimateRules()},
+		{"newgame misere=true", game.Rules{Size: 3, K: 3, Misere: true}},
+	}
+	for _, tt := range tests {
+		e := New(game.Rules{Size: 4, K: 3}, 1)
+		if _, err := e.Execute("play a1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.Execute(tt.line); err != nil {
+			t.Fatalf("Execute(%q) error = %v", tt.line, err)
+		}
+		if got := e.Game().Rules(); got != tt.want || e.Game().Plies() != 0 {
+			t.Errorf("%s: rules %+v after %d moves, want %+v on an empty board", tt.line, got, e.Game().Plies(), tt.want)
+		}
+	}
+}
+
+func TestGenMove(t *testing.T) {
+	e := New(game.Standard(), 1)
+	for _, line := range []string{"play a1", "play b2", "play a2"} {
+		if _, err := e.Execute(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Perfect play blocks the column
+	got, err := e.Execute("genmove")
+	if err != nil || got != "a3" {
+		t.Fatalf("genmove = %q, %v, want a3", got, err)
+	}
+	if moves, _ := e.Execute("moves"); moves != "a1 b2 a2 a3" {
+		t.Errorf("moves = %q after genmove", moves)
+	}
+}
+
+func TestRun(t *testing.T) {
+	in := strings.Join([]string{
+		"# a comment",
+		"newgame size=3 k=3",
+		"",
+		"play b2",
+		"play b2",
+		"board",
+		"quit",
+		"play a1",
+	}, "\n")
+	var out strings.Builder
+	if err := New(game.Standard(), 1).Run(strings.NewReader(in), &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := "=\n\n" +
+		"=\n\n" +
+		"? b2: " + game.ErrOccupied.Error() + "\n\n" +
+		"=\n   a b c\n 1 . . .\n 2 . X .\n 3 . . .\n\n" +
+		// Nothing is read after quit
+		"=\n\n"
+	if out.String() != want {
+		t.Errorf("Run() wrote\n%q\nwant\n%q", out.String(), want)
+	}
+}
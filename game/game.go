@@ -0,0 +1,432 @@
+// Package game implements the rules of Tic-Tac-Toe independently of any
+// user interface, so they can be shared by the TUI, bots, servers and tests.
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Player identifies the owner of a cell or the side to move.
+type Player int
+
+const (
+	Empty Player = 0
+	X     Player = 1
+	O     Player = -1
+)
+
+const (
+	// MinSize and MaxSize bound the width and height of the board.
+	MinSize = 3
+	MaxSize = 15
+	// MinK is the shortest line that may be required to win.
+	MinK = 3
+)
+
+// Opponent returns the other side. Empty has no opponent.
+func (p Player) Opponent() Player {
+	return -p
+}
+
+func (p Player) String() string {
+	switch p {
+	case X:
+		return "X"
+	case O:
+		return "O"
+	}
+	return " "
+}
+
+// ParsePlayer accepts "X" or "O".
+func ParsePlayer(s string) (Player, error) {
+	switch s {
+	case "X":
+		return X, nil
+	case "O":
+		return O, nil
+	}
+	return Empty, fmt.Errorf("unknown player %q", s)
+}
+
+// Move is a zero-based cell coordinate.
+type Move struct {
+	Row int
+	Col int
+}
+
+// String names the cell algebraically: a column letter and a one-based row
+// counted from the top, so a1 is the top-left corner and c3 the bottom-right
+// one of a 3x3 board.
+func (m Move) String() string {
+	return fmt.Sprintf("%c%d", 'a'+m.Col, m.Row+1)
+}
+
+// ParseMove reads a cell named like Move.String does. Whether the cell is on
+// the board is left to the rules.
+func ParseMove(name string) (Move, error) {
+	if len(name) < 2 || name[0] < 'a' || name[0] > 'z' {
+		return Move{}, fmt.Errorf("bad cell %q: want a column letter and a row number like b2", name)
+	}
+	row, err := strconv.Atoi(name[1:])
+	if err != nil || row < 1 || name[1] == '+' {
+		return Move{}, fmt.Errorf("bad cell %q: want a column letter and a row number like b2", name)
+	}
+	return Move{Row: row - 1, Col: int(name[0] - 'a')}, nil
+}
+
+var (
+	ErrInvalidRules = errors.New("invalid rules")
+	ErrOutOfBounds  = errors.New("cell is out of bounds")
+	ErrOccupied     = errors.New("cell is already occupied")
+	ErrGameOver     = errors.New("game is already over")
+	ErrWrongTurn    = errors.New("it is not this player's turn")
+)
+
+// MoveError describes why a move was rejected. Use errors.Is with the
+// Err* values to inspect the reason.
+type MoveError struct {
+	Player Player
+	Move   Move
+	Err    error
+}
+
+func (e *MoveError) Error() string {
+	return fmt.Sprintf("illegal move by %s at [%d, %d]: %v", e.Player, e.Move.Row, e.Move.Col, e.Err)
+}
+
+func (e *MoveError) Unwrap() error {
+	return e.Err
+}
+
+// Variant selects which game is played.
+type Variant int
+
+const (
+	// Classic is K in a row on a single board.
+	Classic Variant = iota
+	// Ultimate nests 3x3 boards inside a 3x3 board, see UltimateState.
+	Ultimate
+)
+
+// ParseVariant accepts the lower-case name of a variant.
+func ParseVariant(name string) (Variant, error) {
+	for _, v := range []Variant{Classic, Ultimate} {
+		if strings.EqualFold(name, v.String()) {
+			return v, nil
+		}
+	}
+	return Classic, fmt.Errorf("%w: unknown variant %q", ErrInvalidRules, name)
+}
+
+func (v Variant) String() string {
+	switch v {
+	case Classic:
+		return "Classic"
+	case Ultimate:
+		return "Ultimate"
+	}
+	return fmt.Sprintf("Variant(%d)", int(v))
+}
+
+// Rules selects the variant, the board size and how many markers in a row win.
+type Rules struct {
+	Variant Variant
+	Size    int
+	K       int
+	// Misere turns the goal around: whoever completes a line loses
+	Misere bool
+}
+
+// Standard returns the classic 3x3, three-in-a-row rules.
+func Standard() Rules {
+	return Rules{Size: 3, K: 3}
+}
+
+// UltimateRules returns the rules of Ultimate Tic-Tac-Toe, played on the
+// 9x9 cells of the nested boards.
+func UltimateRules() Rules {
+	return Rules{Variant: Ultimate, Size: 9, K: 3}
+}
+
+// Validate checks that the board fits the supported range and that K fits
+// on the board.
+func (r Rules) Validate() error {
+	if r.Variant == Ultimate && r.Misere {
+		return fmt.Errorf("%w: misère is only played on a single board", ErrInvalidRules)
+	}
+	if r.Variant == Ultimate {
+		if r != UltimateRules() {
+			return fmt.Errorf("%w: ultimate is played on a 9x9 board, 3 in a row", ErrInvalidRules)
+		}
+		return nil
+	}
+	if r.Variant != Classic {
+		return fmt.Errorf("%w: unknown variant %d", ErrInvalidRules, int(r.Variant))
+	}
+	if r.Size < MinSize || r.Size > MaxSize {
+		return fmt.Errorf("%w: board size %d is outside %d..%d", ErrInvalidRules, r.Size, MinSize, MaxSize)
+	}
+	if r.K < MinK || r.K > r.Size {
+		return fmt.Errorf("%w: %d in a row does not fit a %dx%d board", ErrInvalidRules, r.K, r.Size, r.Size)
+	}
+	return nil
+}
+
+func (r Rules) String() string {
+	if r.Variant == Ultimate {
+		return "Ultimate Tic-Tac-Toe"
+	}
+	if r.Misere {
+		return fmt.Sprintf("%dx%d, %d in a row loses", r.Size, r.Size, r.K)
+	}
+	return fmt.Sprintf("%dx%d, %d in a row", r.Size, r.Size, r.K)
+}
+
+// Game is the position of any variant, addressed by the cells of its board.
+type Game interface {
+	Rules() Rules
+	Size() int
+	At(row, col int) Player
+	Turn() Player
+	Plies() int
+	Moves() []Move
+	LastMove() (Move, bool)
+	InBounds(m Move) bool
+	Play(p Player, m Move) error
+	Apply(m Move) error
+	Undo() (Move, bool)
+	LegalMoves() []Move
+	Winner() Player
+	IsDraw() bool
+	IsTerminal() bool
+}
+
+var (
+	_ Game = (*State)(nil)
+	_ Game = (*UltimateState)(nil)
+)
+
+// New returns the starting position of the variant selected by the rules.
+// It panics if the rules do not pass Validate.
+func New(r Rules) Game {
+	if r.Variant == Ultimate {
+		return NewUltimateState()
+	}
+	return NewState(r)
+}
+
+// State is a position together with the side to move.
+type State struct {
+	rules   Rules
+	cells   []Player
+	turn    Player
+	winner  Player
+	history []Move
+}
+
+// NewState returns an empty board with X to move. It panics if the rules
+// do not pass Validate.
+func NewState(r Rules) *State {
+	if err := r.Validate(); err != nil {
+		panic(err)
+	}
+	return &State{
+		rules: r,
+		cells: make([]Player, r.Size*r.Size),
+		turn:  X,
+	}
+}
+
+// Clone returns an independent copy of the state.
+func (s *State) Clone() *State {
+	c := *s
+	c.cells = append([]Player(nil), s.cells...)
+	c.history = append([]Move(nil), s.history...)
+	return &c
+}
+
+// Rules returns the rules the state was created with.
+func (s *State) Rules() Rules {
+	return s.rules
+}
+
+// Size returns the width and height of the board.
+func (s *State) Size() int {
+	return s.rules.Size
+}
+
+// At returns the marker in the given cell.
+func (s *State) At(row, col int) Player {
+	return s.cells[row*s.rules.Size+col]
+}
+
+// Turn returns the side to move.
+func (s *State) Turn() Player {
+	return s.turn
+}
+
+// Plies returns the number of moves played so far.
+func (s *State) Plies() int {
+	return len(s.history)
+}
+
+// Moves returns the moves played so far, oldest first.
+func (s *State) Moves() []Move {
+	return append([]Move(nil), s.history...)
+}
+
+// LastMove returns the most recent move, if any.
+func (s *State) LastMove() (Move, bool) {
+	if len(s.history) == 0 {
+		return Move{}, false
+	}
+	return s.history[len(s.history)-1], true
+}
+
+// InBounds reports whether the move lies on the board.
+func (s *State) InBounds(m Move) bool {
+	return m.Row >= 0 && m.Row < s.rules.Size && m.Col >= 0 && m.Col < s.rules.Size
+}
+
+// Play applies the move on behalf of p, rejecting it if p is not to move.
+func (s *State) Play(p Player, m Move) error {
+	if p != s.turn {
+		return &MoveError{Player: p, Move: m, Err: ErrWrongTurn}
+	}
+	return s.Apply(m)
+}
+
+// Apply places the marker of the side to move and passes the turn.
+func (s *State) Apply(m Move) error {
+	if err := s.check(m); err != nil {
+		return &MoveError{Player: s.turn, Move: m, Err: err}
+	}
+	s.cells[m.Row*s.rules.Size+m.Col] = s.turn
+	s.history = append(s.history, m)
+	if s.completesLine(m) {
+		s.winner = s.turn
+		if s.rules.Misere {
+			s.winner = s.turn.Opponent()
+		}
+	}
+	s.turn = s.turn.Opponent()
+	return nil
+}
+
+// Undo takes back the most recent move and returns it. It reports false when
+// no moves have been played.
+func (s *State) Undo() (Move, bool) {
+	m, ok := s.LastMove()
+	if !ok {
+		return m, false
+	}
+	s.history = s.history[:len(s.history)-1]
+	s.cells[m.Row*s.rules.Size+m.Col] = Empty
+	// Moves are only accepted while nobody has won, so the position before
+	// the last move had no winner either
+	s.winner = Empty
+	s.turn = s.turn.Opponent()
+	return m, true
+}
+
+func (s *State) check(m Move) error {
+	if s.IsTerminal() {
+		return ErrGameOver
+	}
+	if !s.InBounds(m) {
+		return ErrOutOfBounds
+	}
+	if s.At(m.Row, m.Col) != Empty {
+		return ErrOccupied
+	}
+	return nil
+}
+
+// LegalMoves lists the empty cells in row-major order, or nothing once the
+// game is over.
+func (s *State) LegalMoves() []Move {
+	if s.IsTerminal() {
+		return nil
+	}
+	var moves []Move
+	for row := 0; row < s.rules.Size; row++ {
+		for col := 0; col < s.rules.Size; col++ {
+			if s.At(row, col) == Empty {
+				moves = append(moves, Move{Row: row, Col: col})
+			}
+		}
+	}
+	return moves
+}
+
+// Winner returns the side that won, or Empty. That is the side completing a
+// line, or under misère rules its opponent.
+func (s *State) Winner() Player {
+	return s.winner
+}
+
+// IsDraw reports whether the board is full without a winner.
+func (s *State) IsDraw() bool {
+	return s.winner == Empty && len(s.history) == len(s.cells)
+}
+
+// IsTerminal reports whether no further moves can be played.
+func (s *State) IsTerminal() bool {
+	return s.winner != Empty || len(s.history) == len(s.cells)
+}
+
+// directions are the four axes a line can run along: horizontal, vertical
+// and both diagonals.
+var directions = [4]Move{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+
+// completesLine reports whether the marker just placed at m is part of K
+// equal markers in a row. Only lines through m need checking because every
+// earlier move has already been checked.
+func (s *State) completesLine(m Move) bool {
+	marker := s.At(m.Row, m.Col)
+	for _, d := range directions {
+		count := 1 + s.run(m, d, marker) + s.run(m, Move{-d.Row, -d.Col}, marker)
+		if count >= s.rules.K {
+			return true
+		}
+	}
+	return false
+}
+
+// WinningLine returns the cells of the line that decided the game, nil while
+// nobody has won. Lines longer than K are returned whole. Under misère rules
+// the line is the loser's.
+func (s *State) WinningLine() []Move {
+	m, ok := s.LastMove()
+	if s.winner == Empty || !ok {
+		return nil
+	}
+	marker := s.At(m.Row, m.Col)
+	for _, d := range directions {
+		back, forward := s.run(m, Move{-d.Row, -d.Col}, marker), s.run(m, d, marker)
+		if 1+back+forward < s.rules.K {
+			continue
+		}
+		line := make([]Move, 0, 1+back+forward)
+		for i := -back; i <= forward; i++ {
+			line = append(line, Move{m.Row + i*d.Row, m.Col + i*d.Col})
+		}
+		return line
+	}
+	return nil
+}
+
+// run counts consecutive cells holding marker starting next to m in direction d.
+func (s *State) run(m Move, d Move, marker Player) int {
+	count := 0
+	p := Move{m.Row + d.Row, m.Col + d.Col}
+	for s.InBounds(p) && s.At(p.Row, p.Col) == marker {
+		count++
+		p = Move{p.Row + d.Row, p.Col + d.Col}
+	}
+	return count
+}
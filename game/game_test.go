@@ -0,0 +1,246 @@
+package game
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// play applies moves in order to a new game under rules.
+func play(t *testing.T, rules Rules, moves ...Move) *State {
+	t.Helper()
+	s := NewState(rules)
+	for _, m := range moves {
+		if err := s.Apply(m); err != nil {
+			t.Fatalf("%v: %v", m, err)
+		}
+	}
+	return s
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		moves  []Move
+		winner Player
+		draw   bool
+	}{
+		{"no moves", nil, Empty, false},
+		{"row", []Move{{0, 0}, {1, 0}, {0, 1}, {1, 1}, {0, 2}}, X, false},
+		{"column", []Move{{0, 0}, {0, 1}, {1, 0}, {1, 1}, {2, 2}, {2, 1}}, O, false},
+		{"diagonal", []Move{{0, 0}, {0, 1}, {1, 1}, {0, 2}, {2, 2}}, X, false},
+		{"anti-diagonal", []Move{{0, 2}, {0, 0}, {1, 1}, {0, 1}, {2, 0}}, X, false},
+		{"win on the last cell", []Move{{0, 2}, {0, 1}, {2, 1}, {1, 0}, {0, 0}, {1, 2}, {1, 1}, {2, 0}, {2, 2}}, X, false},
+		{"full board", []Move{{1, 1}, {0, 0}, {0, 2}, {2, 0}, {1, 0}, {1, 2}, {0, 1}, {2, 1}, {2, 2}}, Empty, true},
+		{"two in a row", []Move{{0, 0}, {1, 1}, {0, 1}}, Empty, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := play(t, Standard(), tt.moves...)
+			if got := s.Winner(); got != tt.winner {
+				t.Errorf("Winner() = %v, want %v", got, tt.winner)
+			}
+			if got := s.IsDraw(); got != tt.draw {
+				t.Errorf("IsDraw() = %v, want %v", got, tt.draw)
+			}
+			if got, want := s.IsTerminal(), tt.winner != Empty || tt.draw; got != want {
+				t.Errorf("IsTerminal() = %v, want %v", got, want)
+			}
+			if got := s.Plies(); got != len(tt.moves) {
+				t.Errorf("Plies() = %d, want %d", got, len(tt.moves))
+			}
+			if s.IsTerminal() && s.LegalMoves() != nil {
+				t.Errorf("LegalMoves() = %v after the end", s.LegalMoves())
+			}
+		})
+	}
+}
+
+func TestApplyNxN(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  Rules
+		moves  []Move
+		winner Player
+	}{
+		{"four in a row on 5x5", Rules{Size: 5, K: 4}, []Move{{2, 0}, {0, 0}, {2, 1}, {0, 1}, {2, 2}, {0, 2}, {2, 3}}, X},
+		{"three are not enough on 5x5", Rules{Size: 5, K: 4}, []Move{{2, 0}, {0, 0}, {2, 1}, {0, 1}, {2, 2}}, Empty},
+		{"blocked line", Rules{Size: 5, K: 4}, []Move{{2, 0}, {2, 2}, {2, 1}, {0, 0}, {2, 3}, {0, 1}, {2, 4}}, Empty},
+		{"off-centre diagonal", Rules{Size: 6, K: 4}, []Move{{1, 2}, {0, 0}, {2, 3}, {0, 1}, {3, 4}, {0, 2}, {4, 5}}, X},
+		{"anti-diagonal to the edge", Rules{Size: 6, K: 4}, []Move{{0, 0}, {2, 5}, {0, 1}, {3, 4}, {1, 1}, {4, 3}, {5, 5}, {5, 2}}, O},
+		{"gap filled in the middle", Rules{Size: 7, K: 5}, []Move{{3, 0}, {0, 0}, {3, 1}, {0, 1}, {3, 3}, {0, 3}, {3, 4}, {0, 6}, {3, 2}}, X},
+		{"rows do not wrap", Rules{Size: 4, K: 3}, []Move{{0, 2}, {2, 0}, {0, 3}, {2, 1}, {1, 0}}, Empty},
+		{"K as wide as the board", Rules{Size: 4, K: 4}, []Move{{0, 3}, {0, 0}, {1, 2}, {1, 1}, {2, 1}, {2, 2}, {3, 0}}, X},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := play(t, tt.rules, tt.moves...)
+			if got := s.Winner(); got != tt.winner {
+				t.Errorf("Winner() = %v, want %v", got, tt.winner)
+			}
+		})
+	}
+}
+
+func TestApplyMisere(t *testing.T) {
+	misere := Rules{Size: 3, K: 3, Misere: true}
+	tests := []struct {
+		name   string
+		rules  Rules
+		moves  []Move
+		winner Player
+		// line is the line completed, nil when nobody completed one
+		line []Move
+	}{
+		{"completing a row loses", misere, []Move{{0, 0}, {1, 0}, {0, 1}, {1, 1}, {0, 2}}, O, []Move{{0, 0}, {0, 1}, {0, 2}}},
+		{"O loses the same way", misere, []Move{{0, 0}, {0, 1}, {2, 2}, {1, 1}, {2, 0}, {2, 1}}, X, []Move{{0, 1}, {1, 1}, {2, 1}}},
+		{"a full board is still a draw", misere, []Move{{1, 1}, {0, 0}, {0, 2}, {2, 0}, {1, 0}, {1, 2}, {0, 1}, {2, 1}, {2, 2}}, Empty, nil},
+		{"longer boards", Rules{Size: 5, K: 4, Misere: true}, []Move{{2, 0}, {0, 0}, {2, 1}, {0, 1}, {2, 2}, {0, 2}, {2, 3}}, O, []Move{{2, 0}, {2, 1}, {2, 2}, {2, 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := play(t, tt.rules, tt.moves...)
+			if got := s.Winner(); got != tt.winner {
+				t.Errorf("Winner() = %v, want %v", got, tt.winner)
+			}
+			if got := s.WinningLine(); !sameCells(got, tt.line) {
+				t.Errorf("WinningLine() = %v, want %v", got, tt.line)
+			}
+			if want := tt.line != nil || len(tt.moves) == 9; s.IsTerminal() != want {
+				t.Errorf("IsTerminal() = %v, want %v", s.IsTerminal(), want)
+			}
+		})
+	}
+}
+
+// sameCells tells whether a and b hold the same moves in any order.
+func sameCells(a, b []Move) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, m := range a {
+		if !slices.Contains(b, m) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRulesValidate(t *testing.T) {
+	tests := []struct {
+		rules Rules
+		valid bool
+	}{
+		{Standard(), true},
+		{Rules{Size: MaxSize, K: 5}, true},
+		{Rules{Size: 4, K: 4}, true},
+		{Rules{Size: MinSize - 1, K: 2}, false},
+		{Rules{Size: MaxSize + 1, K: 5}, false},
+		{Rules{Size: 5, K: MinK - 1}, false},
+		{Rules{Size: 4, K: 5}, false},
+		{Rules{Size: 4, K: 3, Misere: true}, true},
+		{Rules{Variant: Ultimate, Size: 9, K: 3, Misere: true}, false},
+	}
+	for _, tt := range tests {
+		err := tt.rules.Validate()
+		if tt.valid && err != nil {
+			t.Errorf("%+v: Validate() error = %v", tt.rules, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidRules) {
+			t.Errorf("%+v: Validate() error = %v, want ErrInvalidRules", tt.rules, err)
+		}
+	}
+}
+
+func TestApplyRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		moves []Move
+		move  Move
+		want  error
+	}{
+		{"above the board", nil, Move{-1, 0}, ErrOutOfBounds},
+		{"right of the board", nil, Move{0, 3}, ErrOutOfBounds},
+		{"occupied", []Move{{1, 1}}, Move{1, 1}, ErrOccupied},
+		{"after a win", []Move{{0, 0}, {1, 0}, {0, 1}, {1, 1}, {0, 2}}, Move{2, 2}, ErrGameOver},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := play(t, Standard(), tt.moves...)
+			before := s.Clone()
+			err := s.Apply(tt.move)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.want)
+			}
+			var me *MoveError
+			if !errors.As(err, &me) || me.Move != tt.move || me.Player != before.Turn() {
+				t.Errorf("Apply() error = %#v, want a MoveError for %v by %v", err, tt.move, before.Turn())
+			}
+			if !slices.Equal(s.cells, before.cells) || s.Plies() != before.Plies() || s.Turn() != before.Turn() {
+				t.Errorf("Apply() changed the position on a rejected move")
+			}
+		})
+	}
+}
+
+func TestPlay(t *testing.T) {
+	s := NewState(Standard())
+	err := s.Play(O, Move{0, 0})
+	if !errors.Is(err, ErrWrongTurn) {
+		t.Fatalf("Play(O) error = %v, want ErrWrongTurn", err)
+	}
+	if s.Plies() != 0 {
+		t.Errorf("Play(O) played a move out of turn")
+	}
+	if err := s.Play(X, Move{0, 0}); err != nil {
+		t.Fatalf("Play(X) error = %v", err)
+	}
+	if s.At(0, 0) != X || s.Turn() != O {
+		t.Errorf("after Play(X): At(0, 0) = %v, Turn() = %v, want X and O", s.At(0, 0), s.Turn())
+	}
+}
+
+func TestLegalMoves(t *testing.T) {
+	s := play(t, Standard(), Move{0, 0}, Move{1, 1})
+	got := s.LegalMoves()
+	want := []Move{{0, 1}, {0, 2}, {1, 0}, {1, 2}, {2, 0}, {2, 1}, {2, 2}}
+	if !slices.Equal(got, want) {
+		t.Errorf("LegalMoves() = %v, want %v", got, want)
+	}
+}
+
+func TestClone(t *testing.T) {
+	s := play(t, Standard(), Move{0, 0})
+	c := s.Clone()
+	if err := c.Apply(Move{1, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if s.At(1, 1) != Empty || s.Plies() != 1 {
+		t.Errorf("playing on the clone changed the original")
+	}
+}
+
+func TestUndo(t *testing.T) {
+	s := NewState(Standard())
+	if _, ok := s.Undo(); ok {
+		t.Fatalf("Undo() on an empty board reported a move")
+	}
+
+	moves := []Move{{0, 0}, {1, 0}, {0, 1}, {1, 1}, {0, 2}}
+	s = play(t, Standard(), moves...)
+	for i := len(moves) - 1; i >= 0; i-- {
+		m, ok := s.Undo()
+		if !ok || m != moves[i] {
+			t.Fatalf("Undo() = %v, %v, want %v", m, ok, moves[i])
+		}
+		if s.At(m.Row, m.Col) != Empty || s.IsTerminal() || s.Plies() != i {
+			t.Fatalf("after undoing %v: cell %v, terminal %v, %d plies", m, s.At(m.Row, m.Col), s.IsTerminal(), s.Plies())
+		}
+		if want := []Player{X, O}[i%2]; s.Turn() != want {
+			t.Fatalf("after undoing %v: Turn() = %v, want %v", m, s.Turn(), want)
+		}
+	}
+	if !slices.Equal(s.cells, NewState(Standard()).cells) {
+		t.Errorf("undoing every move did not empty the board")
+	}
+}
@@ -0,0 +1,35 @@
+// Package gametest sets up positions for the tests of the packages that
+// play games.
+package gametest
+
+import (
+	"testing"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// Moves parses cell names like a1 and c3.
+func Moves(t testing.TB, names ...string) []game.Move {
+	t.Helper()
+	moves := make([]game.Move, len(names))
+	for i, name := range names {
+		m, err := game.ParseMove(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moves[i] = m
+	}
+	return moves
+}
+
+// Position plays the named moves on a new board under rules.
+func Position(t testing.TB, rules game.Rules, names ...string) *game.State {
+	t.Helper()
+	s := game.NewState(rules)
+	for i, m := range Moves(t, names...) {
+		if err := s.Apply(m); err != nil {
+			t.Fatalf("%s: %v", names[i], err)
+		}
+	}
+	return s
+}
@@ -0,0 +1,28 @@
+package game
+
+// Symmetries returns the 8 rotations and reflections of a size x size board
+// as permutations of its cells, counted row by row: cell i of a transformed
+// board is cell perm[i] of the original. The first is the identity.
+func Symmetries(size int) [8][]int {
+	var perms [8][]int
+	for t := range perms {
+		perm := make([]int, size*size)
+		for row := 0; row < size; row++ {
+			for col := 0; col < size; col++ {
+				r, c := row, col
+				if t&4 != 0 {
+					r, c = c, r
+				}
+				if t&2 != 0 {
+					r = size - 1 - r
+				}
+				if t&1 != 0 {
+					c = size - 1 - c
+				}
+				perm[row*size+col] = r*size + c
+			}
+		}
+		perms[t] = perm
+	}
+	return perms
+}
@@ -0,0 +1,46 @@
+package game
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSymmetries(t *testing.T) {
+	for _, size := range []int{3, 4} {
+		perms := Symmetries(size)
+		identity := make([]int, size*size)
+		for i := range identity {
+			identity[i] = i
+		}
+		if !slices.Equal(perms[0], identity) {
+			t.Errorf("size %d: first symmetry %v is not the identity", size, perms[0])
+		}
+
+		corners := []int{0, size - 1, size*size - size, size*size - 1}
+		for i, perm := range perms {
+			// Each is a permutation taking corners to corners
+			sorted := slices.Clone(perm)
+			slices.Sort(sorted)
+			if !slices.Equal(sorted, identity) {
+				t.Errorf("size %d: symmetry %d = %v is not a permutation", size, i, perm)
+			}
+			for _, c := range corners {
+				if !slices.Contains(corners, perm[c]) {
+					t.Errorf("size %d: symmetry %d takes corner %d to %d", size, i, c, perm[c])
+				}
+			}
+			for j := range perms[:i] {
+				if slices.Equal(perm, perms[j]) {
+					t.Errorf("size %d: symmetries %d and %d are the same", size, j, i)
+				}
+			}
+		}
+	}
+
+	// The centre of an odd board stays where it is
+	for i, perm := range Symmetries(3) {
+		if perm[4] != 4 {
+			t.Errorf("symmetry %d moves the centre to %d", i, perm[4])
+		}
+	}
+}
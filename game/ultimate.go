@@ -0,0 +1,233 @@
+package game
+
+import "errors"
+
+// ErrWrongBoard rejects an Ultimate move outside the board the previous
+// move sent the player to.
+var ErrWrongBoard = errors.New("move must be played in the highlighted board")
+
+// lines lists the eight three-in-a-row lines of a 3x3 grid by cell index.
+var lines = [8][3]int{
+	{0, 1, 2}, {3, 4, 5}, {6, 7, 8}, // rows
+	{0, 3, 6}, {1, 4, 7}, {2, 5, 8}, // columns
+	{0, 4, 8}, {2, 4, 6}, // diagonals
+}
+
+// UltimateState is a position of Ultimate Tic-Tac-Toe: a 3x3 grid of 3x3
+// boards. Winning a small board claims it, and three claimed boards in a row
+// win the game. The cell a move is played in selects the small board the
+// opponent must play in next, unless that board is already won or full.
+//
+// Moves use the coordinates of the 9x9 cells, so the small board of a move is
+// (Row/3, Col/3) and the cell within it is (Row%3, Col%3).
+type UltimateState struct {
+	cells   [81]Player
+	boards  [9]Player
+	turn    Player
+	winner  Player
+	history []Move
+}
+
+// NewUltimateState returns an empty grid with X to move anywhere.
+func NewUltimateState() *UltimateState {
+	return &UltimateState{turn: X}
+}
+
+// Clone returns an independent copy of the state.
+func (s *UltimateState) Clone() *UltimateState {
+	c := *s
+	c.history = append([]Move(nil), s.history...)
+	return &c
+}
+
+func (s *UltimateState) Rules() Rules {
+	return UltimateRules()
+}
+
+func (s *UltimateState) Size() int {
+	return 9
+}
+
+func (s *UltimateState) At(row, col int) Player {
+	return s.cells[row*9+col]
+}
+
+func (s *UltimateState) Turn() Player {
+	return s.turn
+}
+
+func (s *UltimateState) Plies() int {
+	return len(s.history)
+}
+
+func (s *UltimateState) Moves() []Move {
+	return append([]Move(nil), s.history...)
+}
+
+func (s *UltimateState) LastMove() (Move, bool) {
+	if len(s.history) == 0 {
+		return Move{}, false
+	}
+	return s.history[len(s.history)-1], true
+}
+
+func (s *UltimateState) InBounds(m Move) bool {
+	return m.Row >= 0 && m.Row < 9 && m.Col >= 0 && m.Col < 9
+}
+
+// BoardWinner returns who claimed the small board at (row, col) of the
+// 3x3 grid, or Empty.
+func (s *UltimateState) BoardWinner(row, col int) Player {
+	return s.boards[row*3+col]
+}
+
+// ForcedBoard returns the small board the side to move must play in. It
+// reports false when any open board may be chosen.
+func (s *UltimateState) ForcedBoard() (row, col int, ok bool) {
+	last, played := s.LastMove()
+	if !played || s.IsTerminal() {
+		return 0, 0, false
+	}
+	target := (last.Row%3)*3 + last.Col%3
+	if s.closed(target) {
+		return 0, 0, false
+	}
+	return target / 3, target % 3, true
+}
+
+// Playable reports whether the side to move may play in the small board at
+// (row, col) of the 3x3 grid.
+func (s *UltimateState) Playable(row, col int) bool {
+	if s.IsTerminal() || s.closed(row*3+col) {
+		return false
+	}
+	forcedRow, forcedCol, forced := s.ForcedBoard()
+	return !forced || (forcedRow == row && forcedCol == col)
+}
+
+func (s *UltimateState) Play(p Player, m Move) error {
+	if p != s.turn {
+		return &MoveError{Player: p, Move: m, Err: ErrWrongTurn}
+	}
+	return s.Apply(m)
+}
+
+func (s *UltimateState) Apply(m Move) error {
+	if err := s.check(m); err != nil {
+		return &MoveError{Player: s.turn, Move: m, Err: err}
+	}
+	s.cells[m.Row*9+m.Col] = s.turn
+	s.history = append(s.history, m)
+	board := boardOf(m)
+	s.boards[board] = s.boardLine(board)
+	if s.boards[board] != Empty {
+		s.winner = threeInRow(func(i int) Player { return s.boards[i] })
+	}
+	s.turn = s.turn.Opponent()
+	return nil
+}
+
+func (s *UltimateState) check(m Move) error {
+	if s.IsTerminal() {
+		return ErrGameOver
+	}
+	if !s.InBounds(m) {
+		return ErrOutOfBounds
+	}
+	if s.At(m.Row, m.Col) != Empty {
+		return ErrOccupied
+	}
+	if !s.Playable(m.Row/3, m.Col/3) {
+		return ErrWrongBoard
+	}
+	return nil
+}
+
+func (s *UltimateState) Undo() (Move, bool) {
+	m, ok := s.LastMove()
+	if !ok {
+		return m, false
+	}
+	s.history = s.history[:len(s.history)-1]
+	s.cells[m.Row*9+m.Col] = Empty
+	// Closed boards accept no moves, so the board was open before this move
+	s.boards[boardOf(m)] = Empty
+	s.winner = Empty
+	s.turn = s.turn.Opponent()
+	return m, true
+}
+
+// LegalMoves lists the empty cells of the playable boards in row-major order.
+func (s *UltimateState) LegalMoves() []Move {
+	var moves []Move
+	for row := 0; row < 9; row++ {
+		for col := 0; col < 9; col++ {
+			if s.At(row, col) == Empty && s.Playable(row/3, col/3) {
+				moves = append(moves, Move{Row: row, Col: col})
+			}
+		}
+	}
+	return moves
+}
+
+func (s *UltimateState) Winner() Player {
+	return s.winner
+}
+
+// IsDraw reports whether every small board is closed without three claimed
+// boards in a row.
+func (s *UltimateState) IsDraw() bool {
+	if s.winner != Empty {
+		return false
+	}
+	for board := 0; board < 9; board++ {
+		if !s.closed(board) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *UltimateState) IsTerminal() bool {
+	return s.winner != Empty || s.IsDraw()
+}
+
+// closed reports whether the small board is claimed or full.
+func (s *UltimateState) closed(board int) bool {
+	if s.boards[board] != Empty {
+		return true
+	}
+	for i := 0; i < 9; i++ {
+		if s.cells[cellOf(board, i)] == Empty {
+			return false
+		}
+	}
+	return true
+}
+
+// boardLine returns who holds three in a row on the small board.
+func (s *UltimateState) boardLine(board int) Player {
+	return threeInRow(func(i int) Player { return s.cells[cellOf(board, i)] })
+}
+
+// boardOf returns the index of the small board containing m.
+func boardOf(m Move) int {
+	return (m.Row/3)*3 + m.Col/3
+}
+
+// cellOf converts a cell index within a small board to an index into cells.
+func cellOf(board, i int) int {
+	row := (board/3)*3 + i/3
+	col := (board%3)*3 + i%3
+	return row*9 + col
+}
+
+// threeInRow returns the owner of a complete line of a 3x3 grid, or Empty.
+func threeInRow(at func(i int) Player) Player {
+	for _, line := range lines {
+		if p := at(line[0]); p != Empty && p == at(line[1]) && p == at(line[2]) {
+			return p
+		}
+	}
+	return Empty
+}
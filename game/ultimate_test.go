@@ -0,0 +1,156 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+// at names a cell by its small board and its cell within that board, both
+// counted row by row from 0 to 8.
+func at(board, cell int) Move {
+	return Move{Row: board/3*3 + cell/3, Col: board%3*3 + cell%3}
+}
+
+// ultimateGame is a game O wins by claiming the boards of the left column.
+// O takes the bottom row of board 0 after the first six moves, then board 3
+// after twelve and board 6 with the last move. X claims board 8 on the way.
+var ultimateGame = []Move{
+	at(0, 0), at(0, 8), at(8, 0), at(0, 7), at(7, 0), at(0, 6),
+	at(6, 0), at(3, 8), at(8, 3), at(3, 7), at(7, 3), at(3, 6),
+	at(6, 6), at(6, 8), at(8, 6), at(6, 5), at(5, 6), at(6, 2),
+}
+
+// playUltimate applies the first n moves of ultimateGame.
+func playUltimate(t *testing.T, n int) *UltimateState {
+	t.Helper()
+	s := NewUltimateState()
+	for _, m := range ultimateGame[:n] {
+		if err := s.Apply(m); err != nil {
+			t.Fatalf("%v: %v", m, err)
+		}
+	}
+	return s
+}
+
+func TestUltimateForcedBoard(t *testing.T) {
+	tests := []struct {
+		name  string
+		plies int
+		// board is the board the side to move is sent to, -1 for any
+		board int
+	}{
+		{"first move", 0, -1},
+		{"sent by the cell", 1, 0},
+		{"sent to the corner", 2, 8},
+		{"sent to a claimed board", 7, -1},
+		{"sent to an open board", 8, 8},
+		{"game over", len(ultimateGame), -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := playUltimate(t, tt.plies)
+			row, col, ok := s.ForcedBoard()
+			if got := row*3 + col; ok != (tt.board >= 0) || ok && got != tt.board {
+				t.Errorf("ForcedBoard() = %d, %d, %v, want board %d", row, col, ok, tt.board)
+			}
+			for board := 0; board < 9; board++ {
+				want := !s.IsTerminal() && s.BoardWinner(board/3, board%3) == Empty && (tt.board < 0 || tt.board == board)
+				if got := s.Playable(board/3, board%3); got != want {
+					t.Errorf("Playable(board %d) = %v, want %v", board, got, want)
+				}
+			}
+			for _, m := range s.LegalMoves() {
+				if !s.Playable(m.Row/3, m.Col/3) || s.At(m.Row, m.Col) != Empty {
+					t.Errorf("LegalMoves() lists %v", m)
+				}
+			}
+		})
+	}
+}
+
+func TestUltimateApplyRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		plies int
+		move  Move
+		want  error
+	}{
+		{"outside the forced board", 1, at(4, 4), ErrWrongBoard},
+		{"in a claimed board", 7, at(0, 1), ErrWrongBoard},
+		{"occupied", 1, at(0, 0), ErrOccupied},
+		{"off the grid", 0, Move{Row: 9, Col: 0}, ErrOutOfBounds},
+		{"after the game", len(ultimateGame), at(4, 4), ErrGameOver},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := playUltimate(t, tt.plies)
+			before := *s.Clone()
+			err := s.Apply(tt.move)
+			var me *MoveError
+			if !errors.Is(err, tt.want) || !errors.As(err, &me) || me.Move != tt.move {
+				t.Errorf("Apply(%v) error = %v, want a MoveError for %v", tt.move, err, tt.want)
+			}
+			if s.cells != before.cells || s.boards != before.boards || s.Plies() != before.Plies() {
+				t.Errorf("Apply() changed the position on a rejected move")
+			}
+		})
+	}
+}
+
+func TestUltimateWinner(t *testing.T) {
+	s := playUltimate(t, len(ultimateGame))
+	if s.Winner() != O || s.IsDraw() || !s.IsTerminal() {
+		t.Errorf("Winner() = %v, IsDraw() = %v, want O to have won", s.Winner(), s.IsDraw())
+	}
+	claimed := map[int]Player{0: O, 3: O, 6: O, 8: X}
+	for board := 0; board < 9; board++ {
+		if got := s.BoardWinner(board/3, board%3); got != claimed[board] {
+			t.Errorf("BoardWinner(board %d) = %v, want %v", board, got, claimed[board])
+		}
+	}
+	if moves := s.LegalMoves(); len(moves) != 0 {
+		t.Errorf("LegalMoves() = %v after the end", moves)
+	}
+}
+
+func TestUltimateUndo(t *testing.T) {
+	s := playUltimate(t, len(ultimateGame))
+	for n := len(ultimateGame) - 1; n >= 0; n-- {
+		m, ok := s.Undo()
+		if !ok || m != ultimateGame[n] {
+			t.Fatalf("Undo() = %v, %v, want %v", m, ok, ultimateGame[n])
+		}
+		want := playUltimate(t, n)
+		if s.cells != want.cells || s.boards != want.boards || s.Winner() != want.Winner() || s.Turn() != want.Turn() {
+			t.Fatalf("undoing %v does not restore the position after %d moves", m, n)
+		}
+		row, col, ok := s.ForcedBoard()
+		wantRow, wantCol, wantOK := want.ForcedBoard()
+		if row != wantRow || col != wantCol || ok != wantOK {
+			t.Fatalf("after undoing %v: ForcedBoard() = %d, %d, %v, want %d, %d, %v", m, row, col, ok, wantRow, wantCol, wantOK)
+		}
+	}
+	if _, ok := s.Undo(); ok {
+		t.Errorf("Undo() on an empty grid reported a move")
+	}
+}
+
+func TestUltimateRules(t *testing.T) {
+	if err := UltimateRules().Validate(); err != nil {
+		t.Errorf("UltimateRules().Validate() error = %v", err)
+	}
+	if err := (Rules{Variant: Ultimate, Size: 3, K: 3}).Validate(); !errors.Is(err, ErrInvalidRules) {
+		t.Errorf("Validate() of a 3x3 Ultimate error = %v, want ErrInvalidRules", err)
+	}
+	if g := New(UltimateRules()); g.Size() != 9 || g.Rules() != UltimateRules() {
+		t.Errorf("New(UltimateRules()) = %v on %d cells", g.Rules(), g.Size())
+	}
+	for _, name := range []string{"classic", "Ultimate"} {
+		if _, err := ParseVariant(name); err != nil {
+			t.Errorf("ParseVariant(%q) error = %v", name, err)
+		}
+	}
+	if _, err := ParseVariant("giant"); !errors.Is(err, ErrInvalidRules) {
+		t.Errorf("ParseVariant(giant) error = %v, want ErrInvalidRules", err)
+	}
+}
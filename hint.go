@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/solver"
+)
+
+// hintBudget and hintMaxPositions bound the solver behind the hints, boards
+// it cannot solve in time or in memory get no overlay.
+const (
+	hintBudget       = 2 * time.Second
+	hintMaxPositions = 1 << 20
+)
+
+// hints is the solver's verdict on every empty cell of one position.
+type hints struct {
+	// position names the moves leading to the position analysed
+	position string
+	moves    map[game.Move]solver.Outcome
+	err      error
+}
+
+// hintsMsg carries hints computed in the background.
+type hintsMsg struct {
+	hints hints
+}
+
+// positionKey tells positions apart by the rules and the moves leading to
+// them. It is never empty, unlike the position of hints not computed yet.
+func positionKey(s *game.State) string {
+	return s.Rules().String() + ": " + strings.Join(moveNames(s.Moves()), " ")
+}
+
+// hinter runs the solver behind the hints of one game. It keeps the solver,
+// so positions met again or reached from one another are answered from its
+// table, and runs one analysis at a time: starting one cancels the last.
+type hinter struct {
+	// running is held by the analysis using the solver
+	running sync.Mutex
+
+	mu     sync.Mutex
+	solver *solver.Solver
+	cancel context.CancelFunc
+}
+
+// analyse solves every move of s in the background, cancelling the analysis
+// under way.
+func (h *hinter) analyse(s *game.State) tea.Cmd {
+	state := s.Clone()
+	ctx, cancel := context.WithCancel(context.Background())
+	h.mu.Lock()
+	if h.cancel != nil {
+		h.cancel()
+	}
+	h.cancel = cancel
+	h.mu.Unlock()
+
+	return func() tea.Msg {
+		defer cancel()
+		h.running.Lock()
+		defer h.running.Unlock()
+		result := hints{position: positionKey(state)}
+		if result.err = ctx.Err(); result.err != nil {
+			return hintsMsg{result}
+		}
+
+		sv, err := h.solverFor(state.Rules())
+		if err != nil {
+			result.err = err
+			return hintsMsg{result}
+		}
+		a, err := sv.AnalyzeContext(ctx, state)
+		if errors.Is(err, solver.ErrBudget) {
+			// Boards this large would only fill the table, drop it
+			h.mu.Lock()
+			if h.solver == sv {
+				h.solver = nil
+			}
+			h.mu.Unlock()
+		}
+		result.moves, result.err = a.Moves, err
+		return hintsMsg{result}
+	}
+}
+
+// solverFor returns the solver for rules, made anew when they changed.
+func (h *hinter) solverFor(rules game.Rules) (*solver.Solver, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.solver == nil || h.solver.Rules() != rules {
+		sv, err := solver.New(rules)
+		if err != nil {
+			return nil, err
+		}
+		sv.Budget = hintBudget
+		sv.MaxPositions = hintMaxPositions
+		h.solver = sv
+	}
+	return h.solver, nil
+}
+
+// hintLabel abbreviates an outcome to fit a cell: W3 wins in three plies,
+// L2 loses in two and D draws.
+func hintLabel(o solver.Outcome) string {
+	switch o.Value {
+	case solver.Win:
+		return constants.HintWinStyle.Render(fmt.Sprintf("W%d", o.Plies))
+	case solver.Loss:
+		return constants.HintLossStyle.Render(fmt.Sprintf("L%d", o.Plies))
+	}
+	return constants.HintDrawStyle.Render("D")
+}
+
+// hintCell shows the markers with the outcome of every empty cell written
+// into it, and marker blinking under the cursor.
+func hintCell(s *game.State, h hints, cursorRow, cursorCol int, marker string) func(row, col int) string {
+	cell := markerCell(s, cursorRow, cursorCol, marker)
+	return func(row, col int) string {
+		o, ok := h.moves[game.Move{Row: row, Col: col}]
+		if !ok || (row == cursorRow && col == cursorCol) {
+			return cell(row, col)
+		}
+		return hintLabel(o)
+	}
+}
+
+// hintMessage describes the hints for the cell under the cursor.
+func hintMessage(h hints, cursor game.Move) string {
+	if errors.Is(h.err, solver.ErrBudget) {
+		return "This board is too large to analyse"
+	}
+	if h.err != nil {
+		return formatErrorMessage(h.err.Error())
+	}
+	if o, ok := h.moves[cursor]; ok {
+		return fmt.Sprintf("%s: %s", cursor, o)
+	}
+	return "W: win, D: draw, L: loss, with the plies it takes"
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/archive"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/notation"
+	"github.com/spf13/cobra"
+)
+
+// newHistoryCmd lists the archived games or prints one of them.
+func newHistoryCmd() *cobra.Command {
+	var mode, result string
+	cmd := &cobra.Command{
+		Use:   "history [number]",
+		Short: "List the games played on this machine",
+		Long:  "List the recorded games, newest first. Given the number of a game, print it in notation instead, ready for replay or load.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter, err := archive.ParseFilter(mode, result)
+			if err != nil {
+				return err
+			}
+			records, bad, err := archive.Load()
+			if err != nil {
+				return err
+			}
+			for _, err := range bad {
+				fmt.Fprintf(os.Stderr, "Skipped %v\n", err)
+			}
+			if len(args) == 1 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n < 1 || n > len(records) {
+					return fmt.Errorf("no game number %s, there are %d", args[0], len(records))
+				}
+				return notation.Write(os.Stdout, records[n-1])
+			}
+			return printArchive(os.Stdout, records, filter)
+		},
+	}
+	cmd.Flags().StringVar(&mode, "mode", "", "only games of this mode: hot-seat, computer, direct or lobby")
+	cmd.Flags().StringVar(&result, "result", "", "only games with this result: x, o or draw")
+	return cmd
+}
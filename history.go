@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// historyRows bounds the move pairs shown in the history sidebar.
+const historyRows = 10
+
+// history is the line of moves of a local game. Undone moves stay in it
+// until a different move is played, so they can be redone.
+type history struct {
+	moves []game.Move
+	// ply is the number of moves on the board, moves[ply:] were undone
+	ply int
+}
+
+// record notes a move just played. Replaying the next undone move keeps the
+// rest of the line, any other move replaces it.
+func (h *history) record(m game.Move) {
+	if h.ply < len(h.moves) && h.moves[h.ply] == m {
+		h.ply++
+		return
+	}
+	h.moves = append(h.moves[:h.ply], m)
+	h.ply++
+}
+
+// undo takes back the last move on the board.
+func (h *history) undo(g game.Game) bool {
+	if h.ply == 0 {
+		return false
+	}
+	g.Undo()
+	h.ply--
+	return true
+}
+
+// redo plays the next undone move again.
+func (h *history) redo(g game.Game) bool {
+	if h.ply == len(h.moves) || g.Apply(h.moves[h.ply]) != nil {
+		return false
+	}
+	h.ply++
+	return true
+}
+
+// jump undoes or redoes moves until ply moves are on the board.
+func (h *history) jump(g game.Game, ply int) {
+	for h.ply > ply && h.undo(g) {
+	}
+	for h.ply < ply && h.redo(g) {
+	}
+}
+
+// View lists the moves two to a row, the last one on the board highlighted
+// and undone ones dimmed. Long games show the rows around the current move.
+func (h *history) View(reviewing bool) string {
+	title := "History"
+	if reviewing {
+		title = fmt.Sprintf("Move %d/%d", h.ply, len(h.moves))
+	}
+	lines := []string{constants.HighlightStyle.Render(title)}
+	if len(h.moves) == 0 {
+		lines = append(lines, constants.BlurredStyle.Render("no moves"))
+	}
+
+	rows := (len(h.moves) + 1) / 2
+	first := clamp((h.ply-1)/2-historyRows/2, 0, max(rows-historyRows, 0))
+	for row := first; row < min(first+historyRows, rows); row++ {
+		var line strings.Builder
+		fmt.Fprintf(&line, "%3d.", row+1)
+		for i := 2 * row; i < min(2*row+2, len(h.moves)); i++ {
+			style := constants.NoStyle
+			switch {
+			case i == h.ply-1:
+				style = constants.FocusedStyle
+			case i >= h.ply:
+				style = constants.BlurredStyle
+			}
+			line.WriteString(" " + style.Render(fmt.Sprintf("%-3s", h.moves[i])))
+		}
+		lines = append(lines, line.String())
+	}
+	return constants.HistoryStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
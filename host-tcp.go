@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/protocol"
+)
+
+type spectatorsMsg struct{ count int }
+
+const (
+	// spectatorBacklog is how many messages a spectator may fall behind
+	// before it is dropped.
+	spectatorBacklog = 64
+	// spectatorTimeout bounds a single send to a spectator.
+	spectatorTimeout = 5 * time.Second
+)
+
+// spectator is a watching connection with its own writer, so a slow one
+// never holds up the game.
+type spectator struct {
+	conn *protocol.Conn
+	out  chan protocol.Message
+}
+
+// guestConn is a connection whose hello came from a player, not a spectator.
+type guestConn struct {
+	conn  *protocol.Conn
+	hello protocol.Message
+}
+
+// table keeps the host's port open for as long as the players stay. Every
+// connection gets the host's hello: a guest answering it joins the game or
+// resumes it after a drop, a spectator watches it. The table mirrors the game
+// so spectators can be sent a snapshot and every move after it.
+type table struct {
+	ln net.Listener
+	// offer is what every hello proposes, the rules and sides follow state
+	offer protocol.Offer
+	// guests holds the player waiting to be taken by nextGuest
+	guests  chan guestConn
+	changed chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	// waiting makes sure only one watchCount is pending
+	waiting atomic.Bool
+
+	mu         sync.Mutex
+	state      game.Game
+	self       game.Player
+	guest      *protocol.Conn
+	spectators []*spectator
+}
+
+// openTable listens on port for the session offer is about to host.
+func openTable(ctx context.Context, port string, offer protocol.Offer) (*table, error) {
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, "tcp", ":"+port)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on port %v: %w", port, err)
+	}
+	t := &table{
+		ln:      ln,
+		offer:   offer,
+		guests:  make(chan guestConn, 1),
+		changed: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		state:   game.New(offer.Rules),
+		self:    game.X,
+	}
+	go t.serve()
+	return t, nil
+}
+
+func (t *table) serve() {
+	for {
+		conn, err := t.ln.Accept()
+		if err != nil {
+			return
+		}
+		go t.greet(protocol.NewConn(conn))
+	}
+}
+
+// greet tells players from spectators by their answer to the hello.
+func (t *table) greet(c *protocol.Conn) {
+	t.mu.Lock()
+	offer := t.offer
+	offer.Rules, offer.Guest = t.state.Rules(), t.self.Opponent()
+	hello := protocol.HostHello(offer, t.state)
+	t.mu.Unlock()
+
+	_ = c.SetDeadline(time.Now().Add(handshakeTimeout))
+	reply, err := protocol.Greet(c, hello)
+	if err != nil {
+		c.Close()
+		return
+	}
+	_ = c.SetDeadline(time.Time{})
+
+	if reply.Watch {
+		t.watch(c)
+		return
+	}
+	// The newest player waits for the host in place of any older one, which
+	// is most likely a connection that dropped before the host got to it
+	g := guestConn{conn: c, hello: reply}
+	for {
+		select {
+		case t.guests <- g:
+			return
+		case <-t.done:
+			c.Close()
+			return
+		default:
+		}
+		select {
+		case stale := <-t.guests:
+			_ = stale.conn.Send(protocol.NewError(protocol.CodeUnexpected, "another player connected to this game"))
+			stale.conn.Close()
+		default:
+		}
+	}
+}
+
+// nextGuest waits for a player to connect, for the first game or to resume.
+func (t *table) nextGuest(ctx context.Context) (guestConn, error) {
+	select {
+	case g := <-t.guests:
+		return g, nil
+	case <-t.done:
+		return guestConn{}, net.ErrClosed
+	case <-ctx.Done():
+		return guestConn{}, ctx.Err()
+	}
+}
+
+// seat remembers the connection to the guest, which is told the number of
+// spectators.
+func (t *table) seat(c *protocol.Conn) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.guest = c
+	t.mu.Unlock()
+	if t.count() > 0 {
+		t.tellGuest()
+	}
+}
+
+// watch queues the spectator a snapshot and keeps it until it hangs up.
+func (t *table) watch(c *protocol.Conn) {
+	s := &spectator{conn: c, out: make(chan protocol.Message, spectatorBacklog)}
+	t.mu.Lock()
+	s.out <- protocol.NewSnapshot(t.state)
+	t.spectators = append(t.spectators, s)
+	t.countChanged()
+	t.mu.Unlock()
+	go s.write()
+	t.tellGuest()
+
+	// Spectators have nothing to say, reading only notices them leaving
+	for {
+		if _, err := c.Receive(); err != nil {
+			break
+		}
+	}
+
+	t.mu.Lock()
+	t.drop(s)
+	t.mu.Unlock()
+	t.tellGuest()
+}
+
+// write sends the queued messages until the spectator is dropped. A send
+// that fails or times out hangs up, which ends watch.
+func (s *spectator) write() {
+	for msg := range s.out {
+		_ = s.conn.SetWriteDeadline(time.Now().Add(spectatorTimeout))
+		if err := s.conn.Send(msg); err != nil {
+			s.conn.Close()
+			return
+		}
+	}
+}
+
+// drop removes a spectator, stops its writer and hangs up, t.mu must be
+// held. Its reader in watch then tells the guest.
+func (t *table) drop(s *spectator) {
+	for i, other := range t.spectators {
+		if other == s {
+			t.spectators = append(t.spectators[:i], t.spectators[i+1:]...)
+			close(s.out)
+			s.conn.Close()
+			t.countChanged()
+			return
+		}
+	}
+}
+
+// countChanged wakes the host's screen, t.mu must be held.
+func (t *table) countChanged() {
+	select {
+	case t.changed <- struct{}{}:
+	default:
+	}
+}
+
+// tellGuest sends the guest the number of spectators, outside t.mu since the
+// guest may be slow to read.
+func (t *table) tellGuest() {
+	t.mu.Lock()
+	guest, count := t.guest, len(t.spectators)
+	t.mu.Unlock()
+	if guest != nil {
+		_ = guest.Send(protocol.Message{Type: protocol.TypeSpectators, Count: count})
+	}
+}
+
+// sync shows spectators the game as the host sees it: the new moves when the
+// game went on, a fresh snapshot when a new game started.
+func (t *table) sync(g game.Game, self game.Player) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.self = self
+
+	seen, moves := t.state.Moves(), g.Moves()
+	continues := len(seen) <= len(moves) && g.Rules() == t.state.Rules()
+	for i := 0; continues && i < len(seen); i++ {
+		continues = seen[i] == moves[i]
+	}
+	if !continues {
+		t.state = game.New(g.Rules())
+		for _, m := range moves {
+			_ = t.state.Apply(m)
+		}
+		t.broadcast(protocol.NewSnapshot(t.state))
+		return
+	}
+	for _, m := range moves[len(seen):] {
+		player := t.state.Turn()
+		_ = t.state.Apply(m)
+		t.broadcast(protocol.NewMove(player, m, t.state.Plies()))
+	}
+}
+
+// resigned tells spectators who gave up.
+func (t *table) resigned(p game.Player) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.broadcast(protocol.Message{Type: protocol.TypeResign, Player: p.String()})
+}
+
+// broadcast queues a message for every spectator, t.mu must be held. A
+// spectator too far behind to take it is dropped.
+func (t *table) broadcast(msg protocol.Message) {
+	for _, s := range append([]*spectator(nil), t.spectators...) {
+		select {
+		case s.out <- msg:
+		default:
+			t.drop(s)
+		}
+	}
+}
+
+func (t *table) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.spectators)
+}
+
+// watchCount waits for the number of spectators to change.
+func (t *table) watchCount() tea.Cmd {
+	if t == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		if !t.waiting.CompareAndSwap(false, true) {
+			return nil
+		}
+		defer t.waiting.Store(false)
+		select {
+		case <-t.changed:
+			return spectatorsMsg{count: t.count()}
+		case <-t.done:
+			return nil
+		}
+	}
+}
+
+// close stops listening and sends the spectators home.
+func (t *table) close() {
+	if t == nil {
+		return
+	}
+	t.once.Do(func() {
+		close(t.done)
+		t.ln.Close()
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		for _, s := range t.spectators {
+			s.conn.Close()
+		}
+	})
+}
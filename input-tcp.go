@@ -2,23 +2,29 @@ package main
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/cursor"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/clock"
 	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
 )
 
 const (
-	waitPlaceholder    = "Are you Client? Type C otherwise !C"
+	waitPlaceholder    = "C: join, S: watch, empty: host"
 	ipPlaceholder      = "IP Address"
 	portPlaceholder    = "Port"
+	timeoutPlaceholder = "Timeout in seconds"
 	submitButtonText   = "Submit"
 	cursorModeHelp     = "cursor mode is %s (ctrl+r to change style)"
 	defaultIPAddress   = "localhost"
 	defaultPort        = "8080"
+	defaultTimeout     = 2 * time.Minute
 	maxErrorMessageLen = 60 // Maximum length of the error message
 )
 
@@ -34,13 +40,19 @@ type TcpInputModel struct {
 	height       int
 	width        int
 	errorMessage string
+	rules        game.Rules
+	control      clock.Control
+	name         string
 }
 
-func NewTCPInputModel(width, height int) TcpInputModel {
+func NewTCPInputModel(width, height int, rules game.Rules, control clock.Control, name string) TcpInputModel {
 	m := TcpInputModel{
-		inputs: make([]textinput.Model, 3),
-		width:  width,
-		height: height,
+		inputs:  make([]textinput.Model, 4),
+		width:   width,
+		height:  height,
+		rules:   rules,
+		control: control,
+		name:    name,
 	}
 
 	var t textinput.Model
@@ -64,6 +76,10 @@ func NewTCPInputModel(width, height int) TcpInputModel {
 			t.Placeholder = portPlaceholder
 			t.Width = len(portPlaceholder)
 			t.CharLimit = 5
+		case 3:
+			t.Placeholder = timeoutPlaceholder
+			t.Width = len(timeoutPlaceholder)
+			t.CharLimit = 4
 		}
 
 		m.inputs[i] = t
@@ -103,7 +119,9 @@ func (m TcpInputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Did the user press enter while the submit button was focused?
 			// If so, attempt to start the game.
 			if s == constants.Enter && m.focusIndex == len(m.inputs) {
-				wait := strings.ToUpper(m.inputs[0].Value()) != "C"
+				role := strings.ToUpper(m.inputs[0].Value())
+				watch := role == "S"
+				wait := role != "C" && !watch
 				ip := m.inputs[1].Value()
 				port := m.inputs[2].Value()
 
@@ -114,14 +132,18 @@ func (m TcpInputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if port == "" {
 					port = defaultPort
 				}
-				//after submit button freeze because waiting for connection
-				conn, player, err := setupConnection(wait, ip, port)
-				if err != nil {
-					m.errorMessage = formatErrorMessage(err.Error())
-					return m, nil
+				timeout := defaultTimeout
+				if value := m.inputs[3].Value(); value != "" {
+					seconds, err := strconv.Atoi(value)
+					if err != nil || seconds <= 0 {
+						m.errorMessage = "Timeout must be a positive number of seconds"
+						return m, nil
+					}
+					timeout = time.Duration(seconds) * time.Second
 				}
-				game := newTCPModel(m.width, m.height, &conn, player)
-				return game, nil
+				m.errorMessage = ""
+				waiting := NewWaitingModel(m, wait, watch, ip, port, timeout)
+				return waiting, waiting.Init()
 			}
 
 			// Cycle indexes
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/profile"
+)
+
+// LeaderboardModel ranks the profiles of this machine by rating.
+type LeaderboardModel struct {
+	width        int
+	height       int
+	board        []profile.Profile
+	errorMessage string
+}
+
+func NewLeaderboardModel(width, height int) LeaderboardModel {
+	m := LeaderboardModel{width: width, height: height}
+	book, err := profile.Load()
+	if err != nil {
+		m.errorMessage = formatErrorMessage(err.Error())
+		return m
+	}
+	m.board = book.Leaderboard()
+	return m
+}
+
+func (m LeaderboardModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m LeaderboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case constants.M:
+			return initialModel(m.width, m.height), nil
+		case constants.Quit, constants.CtrlC, constants.Esc:
+			return m, tea.Quit
+		}
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	}
+	return m, nil
+}
+
+// printLeaderboard writes the profiles as a table, best rated first.
+func printLeaderboard(w io.Writer, board []profile.Profile) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "#\tNAME\tRATING\tGAMES\tW\tD\tL\t")
+	for i, p := range board {
+		fmt.Fprintf(tw, "%d\t%s\t%.0f\t%d\t%d\t%d\t%d\t\n", i+1, p.Name, p.Rating, p.Games(), p.Wins, p.Draws, p.Losses)
+	}
+	return tw.Flush()
+}
+
+func (m LeaderboardModel) View() string {
+	header := constants.HeaderStyle.Render("Leaderboard\n")
+
+	var table strings.Builder
+	_ = printLeaderboard(&table, m.board)
+	lines := strings.Split(strings.TrimRight(table.String(), "\n"), "\n")
+	lines[0] = constants.HighlightStyle.Render(lines[0])
+	if len(m.board) == 0 {
+		lines = append(lines, constants.BlurredStyle.Render("No rated games yet."))
+	}
+
+	errorMsg := ""
+	if m.errorMessage != "" {
+		errorMsg = constants.TCPErrStyle.Render(m.errorMessage)
+	}
+
+	footer := constants.SubtleStyle.Render("m: menu | q, esc: quit")
+
+	view := lipgloss.JoinVertical(lipgloss.Center,
+		header,
+		lipgloss.JoinVertical(lipgloss.Left, lines...),
+		errorMsg,
+		footer,
+	)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, view)
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+// newLoadCmd continues a saved game.
+func newLoadCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "load <file>",
+		Short: "Continue a saved game",
+		Long:  "Continue a game saved with 's' as a hot-seat game on this machine.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := loadGame(args[0])
+			if err != nil {
+				return err
+			}
+			_, err = tea.NewProgram(m, tea.WithAltScreen()).Run()
+			return err
+		},
+	}
+}
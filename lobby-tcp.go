@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/clock"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/protocol"
+)
+
+const defaultLobbyAddress = "localhost:8080"
+
+type lobbyStep int
+
+const (
+	lobbyAddress lobbyStep = iota // typing the address of the lobby
+	lobbyBrowse                   // picking a room
+	lobbyName                     // naming a new room
+	lobbyCode                     // typing the code of a room
+	lobbyWaiting                  // waiting to be matched
+)
+
+type lobbyConnectedMsg struct{ conn *protocol.Conn }
+
+// LobbyModel finds an opponent through a lobby server: it lists the open
+// rooms, creates and joins rooms and asks for automatic matches. Once the
+// lobby pairs two players the game starts like a direct TCP game.
+type LobbyModel struct {
+	width        int
+	height       int
+	step         lobbyStep
+	input        textinput.Model
+	spinner      spinner.Model
+	rules        game.Rules
+	control      clock.Control
+	name         string
+	addr         string
+	conn         *protocol.Conn
+	rooms        []protocol.Room
+	cursor       int
+	code         string
+	errorMessage string
+	ctx          context.Context
+	cancel       context.CancelFunc
+}
+
+func NewLobbyModel(width, height int, rules game.Rules, control clock.Control, name string) LobbyModel {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = constants.FocusedStyle
+
+	m := LobbyModel{
+		width:   width,
+		height:  height,
+		spinner: s,
+		rules:   rules,
+		control: control,
+		name:    name,
+	}
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	m.prompt(lobbyAddress, "Lobby address", defaultLobbyAddress)
+	return m
+}
+
+// prompt moves to a step that asks for text.
+func (m *LobbyModel) prompt(step lobbyStep, placeholder, value string) {
+	m.step = step
+	m.input = textinput.New()
+	m.input.Placeholder = placeholder
+	m.input.SetValue(value)
+	m.input.Width = 32
+	m.input.CharLimit = 64
+	switch step {
+	case lobbyName:
+		m.input.CharLimit = protocol.MaxRoomName
+	case lobbyCode:
+		m.input.CharLimit = protocol.MaxRoomCode
+	}
+	m.input.Cursor.Style = constants.FocusedStyle
+	m.input.PromptStyle = constants.FocusedStyle
+	m.input.TextStyle = constants.FocusedStyle
+	m.input.Focus()
+}
+
+func (m LobbyModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// connect reaches the lobby in the background.
+func (m LobbyModel) connect() tea.Cmd {
+	ctx, addr := m.ctx, m.addr
+	return func() tea.Msg {
+		c, err := dialLobby(ctx, addr)
+		if err != nil {
+			return connectFailedMsg{err: err}
+		}
+		return lobbyConnectedMsg{conn: c}
+	}
+}
+
+// start plays the hello on the connection the lobby has just paired.
+func (m LobbyModel) start(matched protocol.Message) tea.Cmd {
+	ctx, c, addr, rules, control, name := m.ctx, m.conn, m.addr, m.rules, m.control, m.name
+	return func() tea.Msg {
+		session, player, rules, err := startMatched(ctx, c, addr, matched, rules, control, name)
+		if err != nil {
+			return connectFailedMsg{err: err}
+		}
+		return connectedMsg{conn: c, session: session, player: player, rules: rules}
+	}
+}
+
+func (m LobbyModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == constants.CtrlC {
+			m.leave()
+			return m, tea.Quit
+		}
+		return m.key(msg)
+
+	case lobbyConnectedMsg:
+		if m.step != lobbyWaiting || m.conn != nil {
+			// Cancelled while connecting
+			msg.conn.Close()
+			return m, nil
+		}
+		m.conn = msg.conn
+		m.step = lobbyBrowse
+		return m, tea.Batch(m.send(protocol.NewLobby(protocol.ActionList, m.rules)), receive(m.conn))
+
+	case connectFailedMsg:
+		if m.step != lobbyWaiting {
+			return m, nil
+		}
+		m.hangUp()
+		m.prompt(lobbyAddress, "Lobby address", m.addr)
+		m.errorMessage = formatErrorMessage(msg.err.Error())
+		return m, textinput.Blink
+
+	case connectedMsg:
+		if m.step != lobbyWaiting {
+			msg.conn.Close()
+			return m, nil
+		}
+		m.cancel()
+		game := newTCPModel(m.width, m.height, msg.conn, msg.session, msg.player, msg.rules)
+		return game, game.Init()
+
+	case peerMessage:
+		if msg.conn != m.conn {
+			return m, nil
+		}
+		return m.lobbyMessage(msg.msg)
+
+	case errMsg:
+		if msg.conn != m.conn {
+			return m, nil
+		}
+		m.hangUp()
+		m.prompt(lobbyAddress, "Lobby address", m.addr)
+		m.errorMessage = formatErrorMessage("Lost the lobby: " + msg.err.Error())
+		return m, textinput.Blink
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m LobbyModel) key(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.errorMessage = ""
+	switch m.step {
+	case lobbyAddress:
+		switch msg.String() {
+		case constants.Esc:
+			m.leave()
+			return initialModel(m.width, m.height), nil
+		case constants.Enter:
+			m.addr = strings.TrimSpace(m.input.Value())
+			if m.addr == "" {
+				m.addr = defaultLobbyAddress
+			}
+			m.step = lobbyWaiting
+			return m, tea.Batch(m.spinner.Tick, m.connect())
+		}
+
+	case lobbyBrowse:
+		switch msg.String() {
+		case constants.Esc:
+			m.leave()
+			return initialModel(m.width, m.height), nil
+		case constants.Up:
+			m.cursor = max(m.cursor-1, 0)
+		case constants.Down:
+			m.cursor = min(m.cursor+1, max(len(m.rooms)-1, 0))
+		case constants.Refresh:
+			return m, m.send(protocol.NewLobby(protocol.ActionList, m.rules))
+		case constants.Create:
+			m.prompt(lobbyName, "Room name", "")
+			return m, textinput.Blink
+		case constants.JoinCode:
+			m.prompt(lobbyCode, "Room code", "")
+			return m, textinput.Blink
+		case constants.Auto:
+			return m.request(protocol.NewLobby(protocol.ActionQuick, m.rules))
+		case constants.Enter:
+			if m.cursor < len(m.rooms) {
+				return m.join(m.rooms[m.cursor].Code)
+			}
+		}
+		return m, nil
+
+	case lobbyName, lobbyCode:
+		switch msg.String() {
+		case constants.Esc:
+			m.step = lobbyBrowse
+			return m, nil
+		case constants.Enter:
+			if m.step == lobbyCode {
+				return m.join(m.input.Value())
+			}
+			create := protocol.NewLobby(protocol.ActionCreate, m.rules)
+			create.Name = strings.TrimSpace(m.input.Value())
+			return m.request(create)
+		}
+
+	case lobbyWaiting:
+		if msg.String() == constants.Esc {
+			// Hanging up closes our room on the server
+			m.cancel()
+			m.ctx, m.cancel = context.WithCancel(context.Background())
+			m.hangUp()
+			m.prompt(lobbyAddress, "Lobby address", m.addr)
+			m.errorMessage = "Cancelled"
+			return m, textinput.Blink
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// lobbyMessage handles what the lobby says before we are matched.
+func (m LobbyModel) lobbyMessage(msg protocol.Message) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.Type == protocol.TypeError:
+		m.errorMessage = formatErrorMessage("Lobby: " + msg.Text)
+		if m.step == lobbyWaiting {
+			m.step = lobbyBrowse
+			return m, tea.Batch(m.send(protocol.NewLobby(protocol.ActionList, m.rules)), receive(m.conn))
+		}
+	case msg.Type != protocol.TypeLobby:
+		m.errorMessage = fmt.Sprintf("Ignoring unexpected %s message.", msg.Type)
+	case msg.Action == protocol.ActionRooms:
+		m.rooms = msg.Rooms
+		m.cursor = min(m.cursor, max(len(m.rooms)-1, 0))
+	case msg.Action == protocol.ActionRoom:
+		m.code = msg.Room
+	case msg.Action == protocol.ActionMatched:
+		// The hello follows on this connection, stop listening for the lobby
+		m.code = msg.Room
+		return m, m.start(msg)
+	}
+	return m, receive(m.conn)
+}
+
+// join asks for the room with code.
+func (m LobbyModel) join(code string) (tea.Model, tea.Cmd) {
+	code = protocol.NormalizeCode(code)
+	if code == "" || len(code) > protocol.MaxRoomCode {
+		m.errorMessage = "Type the code of a room"
+		return m, nil
+	}
+	join := protocol.NewLobby(protocol.ActionJoin, m.rules)
+	join.Room = code
+	return m.request(join)
+}
+
+// request sends a request that leads to a match and waits for it.
+func (m LobbyModel) request(msg protocol.Message) (tea.Model, tea.Cmd) {
+	if err := m.conn.Send(msg); err != nil {
+		m.hangUp()
+		m.prompt(lobbyAddress, "Lobby address", m.addr)
+		m.errorMessage = formatErrorMessage("Lost the lobby: " + err.Error())
+		return m, textinput.Blink
+	}
+	m.code = msg.Room
+	m.step = lobbyWaiting
+	return m, m.spinner.Tick
+}
+
+// send sends a message whose answer arrives through receive.
+func (m LobbyModel) send(msg protocol.Message) tea.Cmd {
+	c := m.conn
+	return func() tea.Msg {
+		if err := c.Send(msg); err != nil {
+			return errMsg{err: err, conn: c}
+		}
+		return nil
+	}
+}
+
+// hangUp closes the lobby connection but keeps the screen usable.
+func (m *LobbyModel) hangUp() {
+	if m.conn != nil {
+		m.conn.Close()
+		m.conn = nil
+	}
+	m.rooms = nil
+	m.code = ""
+}
+
+// leave stops everything in flight before the screen goes away.
+func (m *LobbyModel) leave() {
+	m.cancel()
+	m.hangUp()
+}
+
+func (m LobbyModel) View() string {
+	title := constants.HeaderStyle.Render("Lobby")
+	var body, footer string
+
+	switch m.step {
+	case lobbyAddress:
+		body = m.input.View()
+		footer = "enter: connect | esc: back to menu"
+	case lobbyName:
+		body = fmt.Sprintf("New room for %s\n\n%s", m.rules, m.input.View())
+		footer = "enter: create | esc: back"
+	case lobbyCode:
+		body = m.input.View()
+		footer = "enter: join | esc: back"
+	case lobbyWaiting:
+		status := fmt.Sprintf("Connecting to %s…", m.addr)
+		switch {
+		case m.code != "":
+			status = fmt.Sprintf("Waiting in room %s…", constants.FocusedStyle.Render(m.code))
+		case m.conn != nil:
+			status = "Waiting for an opponent…"
+		}
+		body = fmt.Sprintf("%s %s", m.spinner.View(), status)
+		if m.code != "" {
+			body += "\n\n" + constants.InfoStyle.Render("Share the code with your opponent.")
+		}
+		footer = "esc: cancel | ctrl+c: quit"
+	case lobbyBrowse:
+		title = constants.HeaderStyle.Render("Lobby " + m.addr)
+		body = m.roomsView()
+		footer = "↑/↓: select | enter: join | c: create | j: join by code | a: auto-match | r: refresh | esc: menu"
+	}
+
+	errorMsg := ""
+	if m.errorMessage != "" {
+		errorMsg = constants.ErrorStyle.Render(m.errorMessage)
+	}
+
+	view := lipgloss.JoinVertical(lipgloss.Center,
+		title,
+		body,
+		errorMsg,
+		constants.SubtleStyle.Render(footer),
+	)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, view)
+}
+
+func (m LobbyModel) roomsView() string {
+	if len(m.rooms) == 0 {
+		return constants.InfoStyle.Render("No open rooms yet, create one or auto-match.")
+	}
+	lines := make([]string, 0, len(m.rooms))
+	for i, room := range m.rooms {
+		rules := room.Variant
+		if r, err := room.Rules(); err == nil {
+			rules = r.String()
+		}
+		line := fmt.Sprintf("%-4s  %-20s  %s", room.Code, room.Name, rules)
+		if i == m.cursor {
+			lines = append(lines, constants.SelectedStyle.Render("[x]  "+line))
+		} else {
+			lines = append(lines, constants.NormalStyle.Render("[ ]  "+line))
+		}
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
@@ -0,0 +1,279 @@
+// Package lobby runs a server where players find each other. Clients speak
+// the lobby messages of the protocol package to list, create and join rooms;
+// once two clients are paired the server relays their messages, so neither
+// has to be reachable by the other.
+package lobby
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/protocol"
+)
+
+// codeAlphabet leaves out letters easily mistaken for digits.
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// codeLength is the length of generated room codes.
+const codeLength = 4
+
+// writeTimeout bounds every send, so a client that stops reading is hung up
+// on instead of stalling the client writing to it.
+const writeTimeout = 10 * time.Second
+
+// client is one connection to the lobby.
+type client struct {
+	conn *protocol.Conn
+	// peer is set once the client is paired, guarded by Server.mu
+	peer *client
+}
+
+// room is a client waiting for an opponent.
+type room struct {
+	host  *client
+	code  string
+	name  string
+	rules game.Rules
+}
+
+// rejoin is one side of a dropped game waiting for the other.
+type rejoin struct {
+	client *client
+	host   bool
+}
+
+// Server pairs clients. The zero value is not usable, call New.
+type Server struct {
+	// Logf reports connections and pairings, log.Printf by default
+	Logf func(format string, args ...any)
+
+	mu      sync.Mutex
+	open    []*room // oldest first
+	rejoins map[string]rejoin
+}
+
+func New() *Server {
+	return &Server{Logf: log.Printf, rejoins: make(map[string]rejoin)}
+}
+
+// ListenAndServe serves on the TCP address addr.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.Logf("lobby listening on %s", ln.Addr())
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln until it is closed.
+func (s *Server) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(&client{conn: protocol.NewConn(conn)})
+	}
+}
+
+func (s *Server) handle(c *client) {
+	defer c.conn.Close()
+	defer s.leave(c)
+
+	for {
+		msg, err := c.conn.Receive()
+		if err != nil {
+			if errors.Is(err, protocol.ErrMalformed) {
+				_ = c.send(protocol.NewError(protocol.CodeMalformed, "%v", err))
+			}
+			return
+		}
+
+		if peer := s.peerOf(c); peer != nil {
+			if err := peer.send(msg); err != nil {
+				return
+			}
+			continue
+		}
+		if msg.Type != protocol.TypeLobby {
+			_ = c.send(protocol.NewError(protocol.CodeUnexpected, "join a room before sending %s", msg.Type))
+			return
+		}
+		replies, err := s.request(c, msg)
+		if err != nil {
+			_ = c.send(protocol.NewError(protocol.CodeUnexpected, "%v", err))
+		}
+		for _, r := range replies {
+			_ = r.to.send(r.msg)
+		}
+	}
+}
+
+// send writes msg to the client. A client that cannot take it is hung up
+// on, which ends its handle and so cleans up after it.
+func (c *client) send(msg protocol.Message) error {
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	err := c.conn.Send(msg)
+	if err != nil {
+		c.conn.Close()
+	}
+	return err
+}
+
+// reply is a message for a client, sent once Server.mu is released so a
+// client that stops reading cannot hold up the others.
+type reply struct {
+	to  *client
+	msg protocol.Message
+}
+
+// request handles a lobby message from a client that is not paired yet and
+// returns the replies to send.
+func (s *Server) request(c *client, msg protocol.Message) ([]reply, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.waiting(c) && msg.Action != protocol.ActionList {
+		return nil, errors.New("already waiting for an opponent")
+	}
+
+	switch msg.Action {
+	case protocol.ActionList:
+		rooms := make([]protocol.Room, 0, min(len(s.open), protocol.MaxRooms))
+		for _, r := range s.open[:min(len(s.open), protocol.MaxRooms)] {
+			rooms = append(rooms, protocol.NewRoom(r.code, r.name, r.rules))
+		}
+		return []reply{{c, protocol.Message{Type: protocol.TypeLobby, Action: protocol.ActionRooms, Rooms: rooms}}}, nil
+
+	case protocol.ActionCreate:
+		rules, _ := msg.Rules()
+		return s.openRoom(c, msg.Name, rules), nil
+
+	case protocol.ActionQuick:
+		rules, _ := msg.Rules()
+		for i, r := range s.open {
+			if r.rules == rules {
+				s.open = append(s.open[:i], s.open[i+1:]...)
+				return s.pair(r.host, c, r.code), nil
+			}
+		}
+		// Nobody to match yet, wait in a room anyone may join
+		return s.openRoom(c, "Quick match", rules), nil
+
+	case protocol.ActionJoin:
+		code := protocol.NormalizeCode(msg.Room)
+		for i, r := range s.open {
+			if r.code == code {
+				s.open = append(s.open[:i], s.open[i+1:]...)
+				return s.pair(r.host, c, r.code), nil
+			}
+		}
+		return nil, fmt.Errorf("no open room %q", code)
+
+	case protocol.ActionRejoin:
+		key := protocol.NormalizeCode(msg.Room) + "/" + msg.Session
+		if other, ok := s.rejoins[key]; ok && other.host != msg.Host {
+			delete(s.rejoins, key)
+			host, guest := other.client, c
+			if msg.Host {
+				host, guest = c, other.client
+			}
+			return s.pair(host, guest, protocol.NormalizeCode(msg.Room)), nil
+		}
+		s.rejoins[key] = rejoin{client: c, host: msg.Host}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%s is not a request", msg.Action)
+}
+
+// openRoom lets c wait for an opponent under a fresh code.
+func (s *Server) openRoom(c *client, name string, rules game.Rules) []reply {
+	r := &room{host: c, code: s.newCode(), name: name, rules: rules}
+	s.open = append(s.open, r)
+	s.Logf("room %s (%s) opened by %s", r.code, rules, c.conn.RemoteAddr())
+	return []reply{{c, protocol.Message{Type: protocol.TypeLobby, Action: protocol.ActionRoom, Room: r.code}}}
+}
+
+// pair connects host and guest. The guest hears first, so it is listening
+// before the host's hello can be relayed to it.
+func (s *Server) pair(host, guest *client, code string) []reply {
+	host.peer, guest.peer = guest, host
+	s.Logf("room %s: %s hosts %s", code, host.conn.RemoteAddr(), guest.conn.RemoteAddr())
+	return []reply{
+		{guest, protocol.Message{Type: protocol.TypeLobby, Action: protocol.ActionMatched, Room: code}},
+		{host, protocol.Message{Type: protocol.TypeLobby, Action: protocol.ActionMatched, Room: code, Host: true}},
+	}
+}
+
+func (s *Server) peerOf(c *client) *client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return c.peer
+}
+
+// waiting reports whether c has a room open or waits to rejoin.
+func (s *Server) waiting(c *client) bool {
+	for _, r := range s.open {
+		if r.host == c {
+			return true
+		}
+	}
+	for _, r := range s.rejoins {
+		if r.client == c {
+			return true
+		}
+	}
+	return false
+}
+
+// leave forgets a client that hung up and hangs up on its peer, which then
+// tries to rejoin.
+func (s *Server) leave(c *client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, r := range s.open {
+		if r.host == c {
+			s.open = append(s.open[:i], s.open[i+1:]...)
+			s.Logf("room %s closed", r.code)
+			break
+		}
+	}
+	for key, r := range s.rejoins {
+		if r.client == c {
+			delete(s.rejoins, key)
+		}
+	}
+	if c.peer != nil {
+		c.peer.conn.Close()
+		c.peer.peer = nil
+		c.peer = nil
+	}
+}
+
+// newCode returns a room code no open room uses.
+func (s *Server) newCode() string {
+	for {
+		code := make([]byte, codeLength)
+		for i := range code {
+			code[i] = codeAlphabet[rand.IntN(len(codeAlphabet))]
+		}
+		taken := false
+		for _, r := range s.open {
+			taken = taken || r.code == string(code)
+		}
+		if !taken {
+			return string(code)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package lobby
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/protocol"
+)
+
+// serve runs a lobby on a loopback port and returns its address.
+func serve(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := New()
+	s.Logf = func(string, ...any) {}
+	go s.Serve(ln)
+	return ln.Addr().String()
+}
+
+// dial connects a client to the lobby, failing the test on any wait longer
+// than a few seconds.
+func dial(t *testing.T, addr string) *protocol.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	c := protocol.NewConn(conn)
+	_ = c.SetDeadline(time.Now().Add(5 * time.Second))
+	return c
+}
+
+func send(t *testing.T, c *protocol.Conn, m protocol.Message) {
+	t.Helper()
+	if err := c.Send(m); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+}
+
+// expect receives the next message and checks its type and action.
+func expect(t *testing.T, c *protocol.Conn, typ protocol.Type, action string) protocol.Message {
+	t.Helper()
+	m, err := c.Receive()
+	if err != nil {
+		t.Fatalf("Receive() error = %v, want %s %s", err, typ, action)
+	}
+	if m.Type != typ || m.Action != action {
+		t.Fatalf("Receive() = %+v, want %s %s", m, typ, action)
+	}
+	return m
+}
+
+// create opens a room and returns its code.
+func create(t *testing.T, c *protocol.Conn, name string, rules game.Rules) string {
+	t.Helper()
+	m := protocol.NewLobby(protocol.ActionCreate, rules)
+	m.Name = name
+	send(t, c, m)
+	return expect(t, c, protocol.TypeLobby, protocol.ActionRoom).Room
+}
+
+// relays checks that a chat from one client reaches the other.
+func relays(t *testing.T, from, to *protocol.Conn) {
+	t.Helper()
+	send(t, from, protocol.Message{Type: protocol.TypeChat, Text: "hi"})
+	if m := expect(t, to, protocol.TypeChat, ""); m.Text != "hi" {
+		t.Errorf("relayed %q, want hi", m.Text)
+	}
+}
+
+func TestCreateJoin(t *testing.T) {
+	addr := serve(t)
+	host, guest := dial(t, addr), dial(t, addr)
+	rules := game.Rules{Size: 5, K: 4}
+	code := create(t, host, "Friday", rules)
+
+	send(t, guest, protocol.NewLobby(protocol.ActionList, game.Standard()))
+	rooms := expect(t, guest, protocol.TypeLobby, protocol.ActionRooms).Rooms
+	if len(rooms) != 1 || rooms[0] != protocol.NewRoom(code, "Friday", rules) {
+		t.Fatalf("rooms = %+v, want only %s", rooms, code)
+	}
+
+	// Codes are not case-sensitive
+	join := protocol.NewLobby(protocol.ActionJoin, game.Standard())
+	join.Room = "  " + string(code[0]+'a'-'A') + code[1:]
+	send(t, guest, join)
+	if m := expect(t, guest, protocol.TypeLobby, protocol.ActionMatched); m.Host || m.Room != code {
+		t.Errorf("guest got %+v, want matched as guest in %s", m, code)
+	}
+	if m := expect(t, host, protocol.TypeLobby, protocol.ActionMatched); !m.Host || m.Room != code {
+		t.Errorf("host got %+v, want matched as host in %s", m, code)
+	}
+	relays(t, host, guest)
+	relays(t, guest, host)
+
+	// The room is taken
+	other := dial(t, addr)
+	send(t, other, protocol.NewLobby(protocol.ActionList, game.Standard()))
+	if rooms := expect(t, other, protocol.TypeLobby, protocol.ActionRooms).Rooms; len(rooms) != 0 {
+		t.Errorf("rooms = %+v after the match, want none", rooms)
+	}
+}
+
+func TestQuickMatch(t *testing.T) {
+	addr := serve(t)
+	first, other, second := dial(t, addr), dial(t, addr), dial(t, addr)
+
+	send(t, first, protocol.NewLobby(protocol.ActionQuick, game.Standard()))
+	code := expect(t, first, protocol.TypeLobby, protocol.ActionRoom).Room
+	// Other rules wait in a room of their own
+	send(t, other, protocol.NewLobby(protocol.ActionQuick, game.Rules{Size: 4, K: 3}))
+	expect(t, other, protocol.TypeLobby, protocol.ActionRoom)
+
+	send(t, second, protocol.NewLobby(protocol.ActionQuick, game.Standard()))
+	if m := expect(t, second, protocol.TypeLobby, protocol.ActionMatched); m.Room != code || m.Host {
+		t.Errorf("second got %+v, want matched as guest in %s", m, code)
+	}
+	if m := expect(t, first, protocol.TypeLobby, protocol.ActionMatched); !m.Host {
+		t.Errorf("first got %+v, want matched as host", m)
+	}
+	relays(t, second, first)
+}
+
+func TestRejoin(t *testing.T) {
+	addr := serve(t)
+	guest, host := dial(t, addr), dial(t, addr)
+
+	rejoin := func(c *protocol.Conn, host bool) {
+		m := protocol.NewLobby(protocol.ActionRejoin, game.Standard())
+		m.Room, m.Session, m.Host = "abcd", "s1", host
+		send(t, c, m)
+	}
+	rejoin(guest, false)
+	rejoin(host, true)
+	if m := expect(t, guest, protocol.TypeLobby, protocol.ActionMatched); m.Host || m.Room != "ABCD" {
+		t.Errorf("guest got %+v, want matched as guest in ABCD", m)
+	}
+	if m := expect(t, host, protocol.TypeLobby, protocol.ActionMatched); !m.Host {
+		t.Errorf("host got %+v, want matched as host", m)
+	}
+	relays(t, host, guest)
+}
+
+func TestRefusals(t *testing.T) {
+	addr := serve(t)
+
+	c := dial(t, addr)
+	join := protocol.NewLobby(protocol.ActionJoin, game.Standard())
+	join.Room = "ZZZZ"
+	send(t, c, join)
+	expect(t, c, protocol.TypeError, "")
+
+	create(t, c, "", game.Standard())
+	send(t, c, protocol.NewLobby(protocol.ActionQuick, game.Standard()))
+	if m := expect(t, c, protocol.TypeError, ""); m.Code != protocol.CodeUnexpected {
+		t.Errorf("second room got %+v, want an %s error", m, protocol.CodeUnexpected)
+	}
+
+	// Game messages before a match end the connection
+	early := dial(t, addr)
+	send(t, early, protocol.Message{Type: protocol.TypeChat, Text: "anyone?"})
+	expect(t, early, protocol.TypeError, "")
+	if _, err := early.Receive(); err == nil {
+		t.Errorf("connection still open after a message before the match")
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	addr := serve(t)
+	host, guest := dial(t, addr), dial(t, addr)
+	code := create(t, host, "", game.Standard())
+	join := protocol.NewLobby(protocol.ActionJoin, game.Standard())
+	join.Room = code
+	send(t, guest, join)
+	expect(t, guest, protocol.TypeLobby, protocol.ActionMatched)
+	expect(t, host, protocol.TypeLobby, protocol.ActionMatched)
+
+	// The peer is hung up on so it tries to rejoin
+	host.Close()
+	if m, err := guest.Receive(); err == nil {
+		t.Errorf("guest received %+v after the host left, want the connection closed", m)
+	}
+
+	// A closed room is gone from the list
+	waiting, lister := dial(t, addr), dial(t, addr)
+	create(t, waiting, "", game.Standard())
+	waiting.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		send(t, lister, protocol.NewLobby(protocol.ActionList, game.Standard()))
+		rooms := expect(t, lister, protocol.TypeLobby, protocol.ActionRooms).Rooms
+		if len(rooms) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("rooms = %+v after the host left, want none", rooms)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestStalledClient checks that a client that stops reading holds up
+// nobody but itself.
+func TestStalledClient(t *testing.T) {
+	s := New()
+	s.Logf = func(string, ...any) {}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go s.Serve(ln)
+
+	// The stalled client sits on an unbuffered pipe, so every write to it
+	// blocks until it reads
+	server, end := net.Pipe()
+	defer end.Close()
+	go s.handle(&client{conn: protocol.NewConn(server)})
+	stalled := protocol.NewConn(end)
+	code := create(t, stalled, "", game.Standard())
+
+	guest := dial(t, ln.Addr().String())
+	join := protocol.NewLobby(protocol.ActionJoin, game.Standard())
+	join.Room = code
+	send(t, guest, join)
+	expect(t, guest, protocol.TypeLobby, protocol.ActionMatched)
+
+	// The match is now being sent to the stalled client
+	other := dial(t, ln.Addr().String())
+	_ = other.SetDeadline(time.Now().Add(time.Second))
+	send(t, other, protocol.NewLobby(protocol.ActionList, game.Standard()))
+	expect(t, other, protocol.TypeLobby, protocol.ActionRooms)
+}
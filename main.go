@@ -1,13 +1,19 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"os"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/ai"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/archive"
 	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/profile"
 	"github.com/spf13/cobra"
 )
 
@@ -17,6 +23,10 @@ const (
 	modeMenu mode = iota
 	modeMultiPlayer
 	modeMultiTCP
+	modeComputer
+	modeDirectTCP
+	modeArchive
+	modeLeaderboard
 )
 
 type menuItem struct {
@@ -32,6 +42,8 @@ type model struct {
 	menuItems []menuItem
 	conn      net.Conn
 	player    string
+	// errorMessage explains why the chosen entry cannot start
+	errorMessage string
 }
 
 func initialModel(width, height int) model {
@@ -42,7 +54,11 @@ func initialModel(width, height int) model {
 		cursor: 0,
 		menuItems: []menuItem{
 			{mode: modeMultiPlayer, name: "Multiplayer"},
-			{mode: modeMultiTCP, name: "Multiplayer TCP"},
+			{mode: modeComputer, name: "Versus Computer"},
+			{mode: modeMultiTCP, name: "Join lobby"},
+			{mode: modeDirectTCP, name: "Direct TCP (IP/port)"},
+			{mode: modeArchive, name: "Past games"},
+			{mode: modeLeaderboard, name: "Leaderboard"},
 		},
 	}
 }
@@ -56,24 +72,59 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch m.mode {
 		case modeMenu:
+			m.errorMessage = ""
 			switch msg.String() {
 			case constants.Up:
 				if m.cursor > 0 {
 					m.cursor--
 				}
 			case constants.Down:
-				if m.cursor < len(m.menuItems)-1 {
+				if m.cursor < len(m.menuItems)+len(settings)-1 {
 					m.cursor++
 				}
+			case constants.Left:
+				m.adjustSetting(-1)
+			case constants.Right:
+				m.adjustSetting(1)
 			case constants.Enter:
-				switch m.cursor {
-				case 0:
-					game := NewGameModel(m.width, m.height)
-					return game, nil
-				case 1:
-					tcpInputModel := NewTCPInputModel(m.width, m.height)
-					return tcpInputModel, nil
-
+				if m.cursor >= len(m.menuItems) {
+					return m, nil
+				}
+				mode := m.menuItems[m.cursor].mode
+				if selectedVariant == game.Ultimate && selectedRules.Misere && mode != modeArchive && mode != modeLeaderboard {
+					m.errorMessage = "Misère is only played on a single board"
+					return m, nil
+				}
+				switch mode {
+				case modeMultiPlayer:
+					picker := NewProfileModel(m.width, m.height, []string{"Player X", "Player O"}, startHotSeat)
+					return picker, picker.Init()
+				case modeComputer:
+					if selectedVariant == game.Ultimate {
+						m.errorMessage = "The computer only plays the classic variant"
+						return m, nil
+					}
+					if err := selectedDifficulty.CheckRules(selectedRules); err != nil {
+						m.errorMessage = formatErrorMessage(err.Error())
+						return m, nil
+					}
+					picker := NewProfileModel(m.width, m.height, []string{"You"}, startComputer)
+					return picker, picker.Init()
+				case modeMultiTCP:
+					picker := NewProfileModel(m.width, m.height, []string{"You"}, func(width, height int, names []string) (tea.Model, tea.Cmd) {
+						lobbyModel := NewLobbyModel(width, height, menuRules(), selectedControl, firstName(names))
+						return lobbyModel, lobbyModel.Init()
+					})
+					return picker, picker.Init()
+				case modeDirectTCP:
+					picker := NewProfileModel(m.width, m.height, []string{"You"}, func(width, height int, names []string) (tea.Model, tea.Cmd) {
+						return NewTCPInputModel(width, height, menuRules(), selectedControl, firstName(names)), nil
+					})
+					return picker, picker.Init()
+				case modeArchive:
+					return NewArchiveModel(m.width, m.height), nil
+				case modeLeaderboard:
+					return NewLeaderboardModel(m.width, m.height), nil
 				}
 			case constants.Quit, constants.CtrlC:
 				return m, tea.Quit
@@ -87,6 +138,49 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// startHotSeat starts a game on this machine, rated when both players
+// chose a profile.
+func startHotSeat(width, height int, names []string) (tea.Model, tea.Cmd) {
+	if selectedVariant == game.Ultimate {
+		ultimate := NewUltimateModel(width, height).withClock(selectedControl)
+		if names != nil {
+			ultimate.withProfiles(names[0], names[1])
+		}
+		return ultimate, ultimate.Init()
+	}
+	game := NewGameModel(width, height, selectedRules).withClock(selectedControl)
+	if names != nil {
+		game.withProfiles(names...)
+	}
+	return game, game.Init()
+}
+
+// startComputer starts a game against the computer, which is rated like a
+// profile of its own.
+func startComputer(width, height int, names []string) (tea.Model, tea.Cmd) {
+	agent := ai.New(selectedDifficulty, time.Now().UnixNano())
+	game := NewComputerGameModel(width, height, selectedRules, agent, selectedSide).withClock(selectedControl)
+	if names != nil {
+		game.withProfiles(names[0])
+	}
+	return game, game.Init()
+}
+
+// firstName is the profile you chose, empty when you stay anonymous.
+func firstName(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+// adjustSetting changes the setting under the cursor.
+func (m model) adjustSetting(delta int) {
+	if i := m.cursor - len(m.menuItems); i >= 0 {
+		settings[i].adjust(delta)
+	}
+}
+
 func choicesView(m model) string {
 	title := "What to do today?\n"
 
@@ -99,12 +193,27 @@ func choicesView(m model) string {
 		choices = append(choices, choiceStr)
 	}
 
+	choices = append(choices, "")
+	for i, setting := range settings {
+		settingStr := constants.NormalStyle.Render("     " + setting.label())
+		if m.cursor == len(m.menuItems)+i {
+			settingStr = constants.SelectedStyle.Render("     " + setting.label())
+		}
+		choices = append(choices, settingStr)
+	}
+
 	menuSelect := lipgloss.JoinVertical(lipgloss.Left, choices...)
-	footer := constants.SubtleStyle.Render("up ↑ / down ↓ : select | enter: choose | q, esc: quit")
+	footer := constants.SubtleStyle.Render("up ↑ / down ↓ : select | ← / → : change | enter: choose | q, esc: quit")
+
+	errorMsg := ""
+	if m.errorMessage != "" {
+		errorMsg = constants.TCPErrStyle.Render(m.errorMessage)
+	}
 
 	view := lipgloss.JoinVertical(lipgloss.Center,
 		constants.TitleStyle.Render(title),
 		menuSelect,
+		errorMsg,
 		constants.SubtleStyle.Render(footer),
 	)
 
@@ -130,10 +239,48 @@ func (m model) View() string {
 }
 
 func main() {
+	var variantName, computerName string
 	var rootCmd = &cobra.Command{
 		Use:   "game",
 		Short: "Tic-Tac-Toe game",
 		Long:  "A simple Tic-Tac-Toe game written in Go using the Bubble Tea library and the Lip Gloss library.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			variant, err := game.ParseVariant(variantName)
+			if err != nil {
+				return err
+			}
+			selectedVariant = variant
+			if variant == game.Ultimate && selectedRules.Misere {
+				return errors.New("--misere is only played on a single board, not with --variant ultimate")
+			}
+			if computerName != "" {
+				if selectedDifficulty, err = ai.ParseDifficulty(computerName); err != nil {
+					return err
+				}
+			}
+			// Without a data directory the learning computer forgets between runs
+			ai.MenaceDir, _ = archive.Dir()
+			if ai.MCTSPlayouts < 0 || ai.MCTSBudget < 0 || ai.MCTSPlayouts == 0 && ai.MCTSBudget == 0 {
+				return errors.New("--playouts and --think-time cannot be negative or both zero")
+			}
+			if err := selectedControl.Validate(); err != nil {
+				return err
+			}
+			if selectedNames[0] != "" {
+				if err := profile.ValidateName(selectedNames[0]); err != nil {
+					return fmt.Errorf("--name: %w", err)
+				}
+			}
+			if err := selectedRules.Validate(); err != nil {
+				return err
+			}
+			if computerName != "" && selectedVariant == game.Classic {
+				if err := selectedDifficulty.CheckRules(selectedRules); err != nil {
+					return fmt.Errorf("--computer: %w", err)
+				}
+			}
+			return nil
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			p := tea.NewProgram(initialModel(0, 0), tea.WithAltScreen())
 			if _, err := p.Run(); err != nil {
@@ -143,6 +290,28 @@ func main() {
 		},
 	}
 
+	rootCmd.AddCommand(newServeCmd())
+	rootCmd.AddCommand(newLoadCmd())
+	rootCmd.AddCommand(newReplayCmd())
+	rootCmd.AddCommand(newHistoryCmd())
+	rootCmd.AddCommand(newStatsCmd())
+	rootCmd.AddCommand(newEngineCmd())
+	rootCmd.AddCommand(newSolveCmd())
+	rootCmd.AddCommand(newTrainCmd())
+	rootCmd.AddCommand(newTournamentCmd())
+
+	rootCmd.PersistentFlags().StringVar(&selectedNames[0], "name", "", "your profile name, offered when a game starts")
+	rootCmd.PersistentFlags().StringVar(&variantName, "variant", "classic", "game to play: classic or ultimate")
+	rootCmd.PersistentFlags().IntVar(&selectedRules.Size, "size", selectedRules.Size, "width and height of the board")
+	rootCmd.PersistentFlags().IntVarP(&selectedRules.K, "k", "k", selectedRules.K, "markers in a row needed to win")
+	rootCmd.PersistentFlags().BoolVar(&selectedRules.Misere, "misere", false, "misère rules: completing a line loses")
+	rootCmd.PersistentFlags().StringVar(&computerName, "computer", "", "level of the computer: easy, medium, perfect, mcts or menace")
+	rootCmd.PersistentFlags().IntVar(&ai.MCTSPlayouts, "playouts", ai.MCTSPlayouts, "random games the mcts computer plays per move, 0 for no limit")
+	rootCmd.PersistentFlags().DurationVar(&ai.MCTSBudget, "think-time", ai.MCTSBudget, "time the mcts computer thinks per move, 0 for no limit")
+	rootCmd.PersistentFlags().DurationVar(&selectedControl.Bank, "time", 0, "time each side has for the whole game, e.g. 3m")
+	rootCmd.PersistentFlags().DurationVar(&selectedControl.Increment, "increment", 0, "time added after every move, e.g. 2s")
+	rootCmd.PersistentFlags().DurationVar(&selectedControl.PerMove, "move-time", 0, "time limit for a single move, e.g. 10s")
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
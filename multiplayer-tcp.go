@@ -1,53 +1,78 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"net"
-	"strconv"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/archive"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/clock"
 	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/notation"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/protocol"
 )
 
-type moveMessage struct{ command string }
-type errMsg struct{ err error }
-
-func (e errMsg) Error() string {
-	return e.err.Error()
-}
-
 type TCPmodel struct {
-	board          [constants.BoardSize][constants.BoardSize]int
+	state          game.Game
 	selectedRow    int
 	selectedColumn int
 	winner         string
-	conn           *net.Conn
-	player         int
-	playerTurn     int
+	conn           *protocol.Conn
+	session        session
+	player         game.Player
 	width          int
 	height         int
 	errorMessage   string
 	infoMessage    string
+	// series scores the games played on this connection, you sit in seat 0
+	series     *series
+	spectators int
+	chat       chatPanel
+	// clock is nil for untimed games, the host's clock decides flags
+	clock   *clock.Clock
+	started time.Time
 }
 
-func newTCPModel(width, height int, conn *net.Conn, player int) TCPmodel {
+func newTCPModel(width, height int, conn *protocol.Conn, session session, player game.Player, rules game.Rules) TCPmodel {
+	s := newSeries("You", "Opponent")
+	if session.self != "" && session.peer != "" && !strings.EqualFold(session.self, session.peer) {
+		// Both sides rate the game on their own machine
+		s = newSeries(session.self, session.peer)
+		s.rated = true
+	}
+	s.xSeat = s.seat(player)
+	c := clock.New(session.control)
+	if c != nil {
+		c.Start(game.X, time.Now())
+	}
 	return TCPmodel{
-		board:          [constants.BoardSize][constants.BoardSize]int{},
+		state:          game.New(rules),
 		selectedRow:    0,
 		selectedColumn: 0,
 		winner:         "",
 		conn:           conn,
+		session:        session,
 		player:         player,
-		playerTurn:     constants.PlayerX,
 		width:          width,
 		height:         height,
+		series:         s,
+		chat:           newChatPanel(),
+		clock:          c,
+		started:        time.Now(),
 	}
 }
 
 func (m TCPmodel) Init() tea.Cmd {
-	return createReceiveMove(*m.conn)
+	return tea.Batch(m.listen(), tickClock(m.clock))
+}
+
+// listen waits for the peer and, on the host, for spectators.
+func (m TCPmodel) listen() tea.Cmd {
+	return tea.Batch(receive(m.conn), m.session.table.watchCount())
 }
 
 func (m TCPmodel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -56,16 +81,27 @@ func (m TCPmodel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 
 	case tea.KeyMsg:
+		if m.chat.open && msg.String() != constants.CtrlC {
+			var cmd tea.Cmd
+			if m, cmd, err = m.chatKey(msg); err != nil {
+				return m.reconnect(err)
+			}
+			return m, cmd
+		}
 		switch msg.String() {
 		case constants.CtrlC, constants.Esc:
 			return m, tea.Quit
 
+		case constants.Tab:
+			var cmd tea.Cmd
+			m.chat, cmd = m.chat.toggle()
+			return m, cmd
 		case constants.Up:
 			if m.selectedRow > 0 {
 				m.selectedRow--
 			}
 		case constants.Down:
-			if m.selectedRow < constants.BoardSize-1 {
+			if m.selectedRow < m.state.Size()-1 {
 				m.selectedRow++
 			}
 		case constants.Left:
@@ -73,47 +109,115 @@ func (m TCPmodel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.selectedColumn--
 			}
 		case constants.Right:
-			if m.selectedColumn < constants.BoardSize-1 {
+			if m.selectedColumn < m.state.Size()-1 {
 				m.selectedColumn++
 			}
+		case constants.Resign:
+			if err := m.conn.Send(protocol.Message{Type: protocol.TypeResign}); err != nil {
+				return m.endGame(formatErrorMessage(err.Error()))
+			}
+			m.session.table.resigned(m.player)
+			return m.finish(m.player.Opponent(), constants.LoseMsgStyle.Render("You resigned."))
+		case constants.Save:
+			m.infoMessage = savedMessage(saveGame(m.record()))
 		case constants.Enter:
 			m, err = m.HandleMyEnter()
+			if err == nil {
+				err = m.sendClock()
+			}
 			if err != nil {
-				m.errorMessage = err.Error()
-				return NewEndGameModel(m.width, m.height, m.errorMessage), nil
+				// The move is in our log and is resent once the game resumes
+				return m.reconnect(err)
 			}
-			if val := m.checkWinner(); val != 0 {
-				winMsg := fmt.Sprintf("Player %s wins!", mapValueToMarker(val))
-				return NewEndGameModel(m.width, m.height, constants.WinMsgStyle.Render(winMsg)), nil
+			if val := m.state.Winner(); val != game.Empty {
+				winMsg := winMessage(m.state.Rules(), val)
+				return m.finish(val, constants.WinMsgStyle.Render(winMsg))
 			}
-			if m.isDraw() {
+			if m.state.IsDraw() {
 				drawMsg := "It's a draw!"
-				return NewEndGameModel(m.width, m.height, constants.DrawMsgStyle.Render(drawMsg)), nil
+				return m.finish(game.Empty, constants.DrawMsgStyle.Render(drawMsg))
 			}
 		}
 
-		return m, createReceiveMove(*m.conn)
-
-	case moveMessage:
-		commandParts := strings.Split(msg.command, ",")
+		return m, nil
 
-		if commandParts[0] == constants.Enter {
-			m, err = m.HandleOpponentEnter(commandParts[0], commandParts[1], commandParts[2], commandParts[3])
+	case peerMessage:
+		if msg.conn != m.conn {
+			return m, nil
+		}
+		switch msg.msg.Type {
+		case protocol.TypeMove:
+			m, err = m.HandleOpponentEnter(msg.msg)
 			if err != nil {
-				m.errorMessage = err.Error()
-				return NewEndGameModel(m.width, m.height, m.errorMessage), nil
+				// Tell the opponent why before hanging up, their board has diverged
+				_ = m.conn.Send(protocol.NewError(protocol.CodeIllegalMove, "%v", err))
+				return m.endGame(constants.LoseMsgStyle.Render("Game over: opponent sent an illegal move.\n" + formatErrorMessage(err.Error())))
+			}
+			if err := m.sendClock(); err != nil {
+				return m.reconnect(err)
 			}
-			if val := m.checkWinner(); val != 0 {
-				loseMsg := fmt.Sprintf("Player %s wins!", mapValueToMarker(val))
-				return NewEndGameModel(m.width, m.height, constants.LoseMsgStyle.Render(loseMsg)), nil
+			if val := m.state.Winner(); val != game.Empty {
+				loseMsg := winMessage(m.state.Rules(), val)
+				return m.finish(val, constants.LoseMsgStyle.Render(loseMsg))
 			}
-			if m.isDraw() {
+			if m.state.IsDraw() {
 				drawMsg := "It's a draw!"
-				return NewEndGameModel(m.width, m.height, constants.DrawMsgStyle.Render(drawMsg)), nil
+				return m.finish(game.Empty, constants.DrawMsgStyle.Render(drawMsg))
+			}
+		case protocol.TypeResign:
+			m.session.table.resigned(m.player.Opponent())
+			return m.finish(m.player, constants.WinMsgStyle.Render("Your opponent resigned. You win!"))
+		case protocol.TypeError:
+			if msg.msg.Code == protocol.CodeIllegalMove {
+				return m.endGame("Game over: opponent rejected our move.\n" + formatErrorMessage(msg.msg.Text))
+			}
+			return m.endGame(formatErrorMessage("Opponent reported an error: " + msg.msg.Text))
+		case protocol.TypeSpectators:
+			m.spectators = msg.msg.Count
+		case protocol.TypeChat:
+			m.chat = m.chat.add(constants.FocusedStyle.Render(m.peerName()+":"), msg.msg.Text)
+		case protocol.TypeClock:
+			if m.clock == nil || m.session.host {
+				break
+			}
+			if flagged, _ := game.ParsePlayer(msg.msg.Clock.Flagged); flagged != game.Empty {
+				m.clock.Flag(flagged)
+				return m.flagged(flagged)
 			}
+			if msg.msg.Ply == m.state.Plies() {
+				x, o, move := msg.msg.Times()
+				m.clock.Sync(x, o, move, m.state.Turn(), time.Now())
+			}
+		default:
+			m.infoMessage = fmt.Sprintf("Ignoring unexpected %s message.", msg.msg.Type)
 		}
 
-		return m, nil
+		return m, receive(m.conn)
+
+	case spectatorsMsg:
+		m.spectators = msg.count
+		return m, m.session.table.watchCount()
+
+	case clockTickMsg:
+		if msg.clock != m.clock {
+			return m, nil
+		}
+		// Only the host's clock flags, the guest's is for show
+		if flagged := m.clock.Check(msg.at); flagged != game.Empty && m.session.host {
+			_ = m.sendClock()
+			return m.flagged(flagged)
+		}
+		return m, tickClock(m.clock)
+
+	case errMsg:
+		if msg.conn != m.conn {
+			return m, nil
+		}
+		if errors.Is(msg.err, protocol.ErrMalformed) {
+			_ = m.conn.Send(protocol.NewError(protocol.CodeMalformed, "%v", msg.err))
+			return m.endGame(formatErrorMessage("Opponent sent a malformed message: " + msg.err.Error()))
+		}
+		return m.reconnect(msg.err)
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -123,55 +227,125 @@ func (m TCPmodel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m TCPmodel) View() string {
-	var cells []string
-	for i := 0; i < constants.BoardSize*constants.BoardSize; i++ {
-		row, col := i/constants.BoardSize, i%constants.BoardSize
-		cell := m.board[row][col]
-		cellStr := " "
-
-		style := constants.CellStyle
-
-		if row == m.selectedRow && col == m.selectedColumn {
-			cellStr = constants.BlinkingStyle.Render(m.getCurrentUser())
-		} else if cell == constants.PlayerX {
-			cellStr = "X"
-		} else if cell == constants.PlayerO {
-			cellStr = "O"
-		}
+// peerName is the opponent's profile name, when they sent one.
+func (m TCPmodel) peerName() string {
+	if m.session.peer != "" {
+		return m.session.peer
+	}
+	return "Opponent"
+}
 
-		// Border styling for the cells because if we set a border for each side it has a margin
-		if col == 0 || col == 2 {
-			style = style.UnsetBorderLeft().UnsetBorderRight().UnsetBorderBottom()
-			if row == 0 {
-				style = style.UnsetBorderStyle()
-			}
-		}
-		if col == 1 {
-			style = style.UnsetBorderBottom()
-			if row == 0 {
-				style = style.UnsetBorderTop()
-			}
-		}
+// sendClock lets the host tell the guest the authoritative clock.
+func (m TCPmodel) sendClock() error {
+	if m.clock == nil || !m.session.host {
+		return nil
+	}
+	return m.conn.Send(protocol.NewClock(m.clock, m.state.Plies()))
+}
+
+// flagged ends the game lost on time.
+func (m TCPmodel) flagged(flagged game.Player) (tea.Model, tea.Cmd) {
+	if flagged == m.player {
+		return m.finish(flagged.Opponent(), constants.LoseMsgStyle.Render("You ran out of time."))
+	}
+	return m.finish(flagged.Opponent(), constants.WinMsgStyle.Render("Your opponent ran out of time. You win!"))
+}
+
+// chatKey handles a key while the chat is open and sends what was typed.
+func (m TCPmodel) chatKey(msg tea.KeyMsg) (TCPmodel, tea.Cmd, error) {
+	switch msg.String() {
+	case constants.Tab, constants.Esc:
+		var cmd tea.Cmd
+		m.chat, cmd = m.chat.toggle()
+		return m, cmd, nil
+	}
+	var cmd tea.Cmd
+	var text string
+	m.chat, cmd, text = m.chat.update(msg)
+	if text == "" {
+		return m, cmd, nil
+	}
+	if err := m.conn.Send(protocol.Message{Type: protocol.TypeChat, Text: text}); err != nil {
+		return m, cmd, err
+	}
+	m.chat = m.chat.add(constants.BlurredStyle.Render("You:"), text)
+	return m, cmd, nil
+}
+
+// withChat puts the open chat beside view.
+func (m TCPmodel) withChat(view string) string {
+	if !m.chat.open {
+		return view
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Center, view, m.chat.View())
+}
 
-		cells = append(cells, style.Render(cellStr))
+// reconnect hangs up the broken connection and tries to resume the game on a
+// new one.
+func (m TCPmodel) reconnect(cause error) (tea.Model, tea.Cmd) {
+	m.conn.Close()
+	reconnecting := NewReconnectModel(m, cause)
+	return reconnecting, reconnecting.Init()
+}
+
+// finish scores a game that ended normally and keeps the connection open so
+// either side can offer a rematch.
+func (m TCPmodel) finish(winner game.Player, message string) (tea.Model, tea.Cmd) {
+	m.series.record(winner)
+	message += m.series.rate(winner)
+	message += archiveGame(m.record(), winner, m.clock)
+	rematch := NewRematchModel(m, message)
+	return rematch, rematch.Init()
+}
+
+// record describes the game so far in notation.
+func (m TCPmodel) record() notation.Record {
+	rec := notation.New(m.state, m.series.name(game.X), m.series.name(game.O), m.started)
+	rec.Mode = archive.ModeDirect
+	if m.session.lobby != "" {
+		rec.Mode = archive.ModeLobby
 	}
+	return rec
+}
+
+// endGame hangs up and shows the outcome.
+func (m TCPmodel) endGame(message string) (tea.Model, tea.Cmd) {
+	m.conn.Close()
+	m.session.table.close()
+	return NewEndGameModel(m.width, m.height, message), nil
+}
+
+func (m TCPmodel) View() string {
+	board := renderPosition(m.state, m.selectedRow, m.selectedColumn, m.getCurrentUser())
 
 	currentPlayer := fmt.Sprintf("I am a %s player: \n", m.getCurrentUser())
+	if m.session.peer != "" {
+		currentPlayer = fmt.Sprintf("I am a %s player against %s: \n", m.getCurrentUser(), m.session.peer)
+	}
+	if m.series.games() > 0 {
+		currentPlayer = fmt.Sprintf("I am a %s player: \n%s\n", m.getCurrentUser(), m.series)
+	}
+	if m.spectators > 0 {
+		currentPlayer += fmt.Sprintf("%d watching\n", m.spectators)
+	}
 
 	header := constants.HeaderStyle.Render(currentPlayer)
 
 	// Instructions
-	footer := constants.SubtleStyle.Render("arrow keys: move | enter: select | ctrl+c or Esc: quit")
-
-	// Joining all cells horizontally
-	board := lipgloss.JoinVertical(lipgloss.Left,
-		lipgloss.JoinHorizontal(lipgloss.Center, cells[0], cells[1], cells[2]),
-		lipgloss.JoinHorizontal(lipgloss.Left, cells[3], cells[4], cells[5]),
-		lipgloss.JoinHorizontal(lipgloss.Left, cells[6], cells[7], cells[8]))
+	footer := constants.SubtleStyle.Render("arrow keys: move | enter: select | r: resign | s: save | " + m.chat.label() + " | ctrl+c or Esc: quit")
+	if m.chat.open {
+		footer = constants.SubtleStyle.Render(m.chat.label() + " | ctrl+c: quit")
+	}
 
 	whoseTurn := fmt.Sprintf("It's %s's turn.\n", m.getCurrentMarker())
 
+	if m.player == game.Empty {
+		// Spectators see the board without a cursor
+		board = renderPosition(m.state, -1, -1, "")
+		header = constants.HeaderStyle.Render("Spectating\n")
+		footer = constants.SubtleStyle.Render("m: menu | ctrl+c or Esc: quit")
+	}
+
 	infoMsg := constants.InfoStyle.Render(m.infoMessage)
 
 	// Joining all elements vertically
@@ -179,128 +353,68 @@ func (m TCPmodel) View() string {
 		header,
 		infoMsg,
 		whoseTurn,
-		constants.BoardStyle.Render(board),
+		lipgloss.JoinHorizontal(lipgloss.Center, constants.BoardStyle.Render(board), renderClocks(m.clock)),
 		m.errorMessage,
 		footer,
 	)
 
-	centeredFullView := lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, view)
+	centeredFullView := lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.withChat(view))
 
 	return centeredFullView
 }
 
 func (m TCPmodel) getCurrentMarker() string {
-	if m.playerTurn == constants.PlayerX {
-		return "X"
-	}
-	return "O"
+	return m.state.Turn().String()
 }
 func (m TCPmodel) getCurrentUser() string {
-	if m.player == constants.PlayerX {
-		return "X"
-	}
-	return "O"
-}
-func mapValueToMarker(value int) string {
-	if value == constants.PlayerX {
-		return "X"
-	}
-	if value == constants.PlayerO {
-		return "O"
-	}
-	return " "
+	return m.player.String()
 }
 
 func (m TCPmodel) HandleMyEnter() (TCPmodel, error) {
-	m = m.handlePlayerEnter(m.player, m.selectedRow, m.selectedColumn)
-	err := m.sendMove(constants.Enter)
+	m, ok := m.handlePlayerEnter(m.player, m.selectedRow, m.selectedColumn)
+	if !ok {
+		return m, nil
+	}
+	m.pressClock()
+	m.session.table.sync(m.state, m.player)
+	err := m.sendMove()
 	return m, err
 }
 
-func (m TCPmodel) HandleOpponentEnter(key, opponentStr, selectedRowStr, selectedColStr string) (TCPmodel, error) {
-	selectedRow, err := strconv.Atoi(selectedRowStr)
+// HandleOpponentEnter applies the opponent's move only if our own position
+// allows it, the opponent cannot overrule our board.
+func (m TCPmodel) HandleOpponentEnter(msg protocol.Message) (TCPmodel, error) {
+	marker := m.getCurrentMarker()
+	move, err := protocol.ApplyMove(m.state, m.player, msg)
 	if err != nil {
 		return m, err
 	}
-
-	selectedCol, err := strconv.Atoi(selectedColStr)
-	if err != nil {
-		return m, err
-	}
-
-	opponent, err := strconv.Atoi(opponentStr)
-	if err != nil {
-		return m, err
-	}
-
-	return m.handlePlayerEnter(opponent, selectedRow, selectedCol), nil
-}
-
-func (m TCPmodel) handlePlayerEnter(player, row, col int) TCPmodel {
-	if player != m.playerTurn {
-		m.infoMessage = fmt.Sprintf("Ignoring %d's move as it's %d's turn.", player, m.playerTurn)
-		return m
-	}
-
-	if m.board[row][col] != constants.Empty {
-		m.infoMessage = fmt.Sprintf("Ignoring %s's move as cell [%d, %d] is not Empty.", m.getCurrentMarker(), row, col)
-		return m
-	}
-
-	m.board[row][col] = player
-	m.infoMessage = fmt.Sprintf("%s marked cell [%d, %d]", m.getCurrentMarker(), row, col)
-
-	m.switchPlayer()
-
-	return m
+	m.pressClock()
+	m.session.table.sync(m.state, m.player)
+	m.infoMessage = fmt.Sprintf("%s marked cell [%d, %d]", marker, move.Row, move.Col)
+	return m, nil
 }
 
-func (m *TCPmodel) checkWinner() int {
-	winningLines := [][3][2]int{
-		{{0, 0}, {0, 1}, {0, 2}}, // first row
-		{{1, 0}, {1, 1}, {1, 2}}, // second row
-		{{2, 0}, {2, 1}, {2, 2}}, // third row
-		{{0, 0}, {1, 0}, {2, 0}}, // first column
-		{{0, 1}, {1, 1}, {2, 1}}, // second column
-		{{0, 2}, {1, 2}, {2, 2}}, // third column
-		{{0, 0}, {1, 1}, {2, 2}}, // diagonal top-left to bottom-right
-		{{0, 2}, {1, 1}, {2, 0}}, // diagonal top-right to bottom-left
+// handlePlayerEnter applies the move to the shared rules and reports whether it was accepted.
+func (m TCPmodel) handlePlayerEnter(player game.Player, row, col int) (TCPmodel, bool) {
+	marker := m.getCurrentMarker()
+	if err := m.state.Play(player, game.Move{Row: row, Col: col}); err != nil {
+		m.infoMessage = fmt.Sprintf("Ignoring %s's move: %v.", player, errors.Unwrap(err))
+		return m, false
 	}
 
-	for _, line := range winningLines {
-		a, b, c := line[0], line[1], line[2]
-		// Check if all cells in the line are the same and not Empty
-		if m.board[a[0]][a[1]] != constants.Empty &&
-			m.board[a[0]][a[1]] == m.board[b[0]][b[1]] &&
-			m.board[a[0]][a[1]] == m.board[c[0]][c[1]] {
-			// Return the winning marker
-			return m.board[a[0]][a[1]]
-		}
-	}
+	m.infoMessage = fmt.Sprintf("%s marked cell [%d, %d]", marker, row, col)
 
-	return 0
+	return m, true
 }
 
-func (m *TCPmodel) isDraw() bool {
-	boardFull := true
-	for i := 0; i < constants.BoardSize; i++ {
-		for j := 0; j < constants.BoardSize; j++ {
-			if m.board[i][j] == constants.Empty {
-				boardFull = false
-				break
-			}
-		}
+func (m TCPmodel) pressClock() {
+	if m.clock != nil {
+		m.clock.Press(time.Now())
 	}
-
-	return boardFull
-}
-
-func (m *TCPmodel) switchPlayer() {
-	m.playerTurn = -m.playerTurn
 }
 
-func (m *TCPmodel) sendMove(key string) error {
-	move := fmt.Sprintf("%s,%d,%d,%d", key, m.player, m.selectedRow, m.selectedColumn)
-	_, err := (*m.conn).Write([]byte((move)))
-	return err
+func (m *TCPmodel) sendMove() error {
+	last, _ := m.state.LastMove()
+	return m.conn.Send(protocol.NewMove(m.player, last, m.state.Plies()))
 }
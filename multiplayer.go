@@ -1,81 +1,180 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/ai"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/archive"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/clock"
 	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/notation"
 )
 
+type computerMoveMsg struct {
+	move game.Move
+	err  error
+}
+
 type GameModel struct {
 	width        int
 	height       int
 	cursor       int
-	board        [3][3]int
-	current      int
-	winner       int
+	state        *game.State
 	blink        bool
-	turnCount    int
 	errorMessage string
+	infoMessage  string
+	// computer plays every side other than human; nil for hot-seat games
+	computer ai.Agent
+	human    game.Player
+	// series scores the games played since leaving the menu
+	series *series
+	// clock is nil for untimed games
+	clock *clock.Clock
+	// history allows undo, redo and reviewing earlier positions
+	history   history
+	reviewing bool
+	started   time.Time
+	// hints overlays the outcome of every empty cell once shown; analysing
+	// keeps them shown and up to date after every move
+	hinter    *hinter
+	hints     hints
+	pending   string
+	showHints bool
+	analysing bool
 }
 
-func NewGameModel(width, height int) *GameModel {
+func NewGameModel(width, height int, rules game.Rules) *GameModel {
 	return &GameModel{
-		width:     width,
-		height:    height,
-		cursor:    0,
-		current:   1, // X starts
-		board:     [3][3]int{},
-		turnCount: 9,
+		width:   width,
+		height:  height,
+		cursor:  0,
+		state:   game.NewState(rules), // X starts
+		series:  newSeries("Player 1", "Player 2"),
+		started: time.Now(),
+		hinter:  &hinter{},
 	}
 }
 
+// NewComputerGameModel starts a game where the human plays one side against agent.
+func NewComputerGameModel(width, height int, rules game.Rules, agent ai.Agent, human game.Player) *GameModel {
+	m := NewGameModel(width, height, rules)
+	m.computer = agent
+	m.human = human
+	m.series = newSeries("You", agent.Name()+" computer")
+	m.series.xSeat = m.series.seat(human)
+	return m
+}
+
+// withProfiles names the seats after profiles, starting with the first
+// seat, and so rates the games.
+func (m *GameModel) withProfiles(names ...string) *GameModel {
+	copy(m.series.names[:], names)
+	m.series.rated = true
+	return m
+}
+
+// withClock times the game under c.
+func (m *GameModel) withClock(c clock.Control) *GameModel {
+	m.clock = clock.New(c)
+	return m
+}
+
 func (m *GameModel) Init() tea.Cmd {
-	return tea.Batch(tea.EnterAltScreen)
+	if m.clock != nil {
+		m.clock.Start(m.state.Turn(), time.Now())
+	}
+	return tea.Batch(tea.EnterAltScreen, m.computerMove(), tickClock(m.clock))
 }
 
 func (m *GameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.reviewing {
+			return m.review(msg)
+		}
 		switch msg.String() {
 		case constants.Up:
-			m.moveCursor(-3)
+			m.moveCursor(-m.state.Size())
 		case constants.Down:
-			m.moveCursor(3)
+			m.moveCursor(m.state.Size())
 		case constants.Left:
 			m.moveCursor(-1)
 		case constants.Right:
 			m.moveCursor(1)
 		case constants.Enter:
-			if !m.placeMarker() {
+			if m.computerToMove() {
+				m.errorMessage = "Wait for the computer's move!"
+			} else if err := m.placeMarker(); errors.Is(err, game.ErrOccupied) {
 				m.errorMessage = "Cannot overwrite existing marker!"
+			} else if err != nil {
+				m.errorMessage = err.Error()
 			} else {
 				m.errorMessage = ""
-				m.winner = m.checkWinner()
-				if m.winner != 0 {
-					endMessage := fmt.Sprintf("Player %s wins!", m.currentMarker())
-					endGameModel := NewEndGameModel(m.width, m.height, constants.WinMsgStyle.Render(endMessage))
-					//sleep for 500 ms for better UX
-					time.Sleep(500 * time.Millisecond)
-					return endGameModel, endGameModel.Init()
-				}
-				m.turnCount--
-				if m.turnCount == 0 {
-					endMessage := "It's a draw!"
-
-					endGameModel := NewEndGameModel(m.width, m.height, constants.DrawMsgStyle.Render(endMessage))
-					//sleep for 500 ms for better UX
-					time.Sleep(500 * time.Millisecond)
-					return endGameModel, endGameModel.Init()
-				}
-				m.switchPlayer()
+				return m.afterMove()
+			}
+		case constants.Undo, constants.CtrlZ:
+			return m.undo()
+		case constants.Redo, constants.CtrlY:
+			return m.redo()
+		case constants.Tab:
+			if m.errorMessage = m.historyLocked(); m.errorMessage == "" {
+				m.reviewing = true
 			}
+		case constants.Save:
+			m.infoMessage = savedMessage(saveGame(m.record()))
+		case constants.Hint:
+			m.showHints = !m.showHints || m.analysing
+			return m, m.requestHints()
+		case constants.Analysis:
+			m.analysing = !m.analysing
+			m.showHints = m.analysing
+			return m, m.requestHints()
 		case constants.CtrlC, constants.Esc:
 			return m, tea.Quit
 		}
 
+	case hintsMsg:
+		if errors.Is(msg.hints.err, context.Canceled) {
+			// A later analysis took over
+			return m, nil
+		}
+		if msg.hints.position == m.pending {
+			m.pending = ""
+		}
+		// Hints of a position left since are dropped, the solver's table
+		// brings them back quickly
+		if msg.hints.position == positionKey(m.state) {
+			m.hints = msg.hints
+		}
+		return m, m.requestHints()
+
+	case computerMoveMsg:
+		if msg.err != nil {
+			m.errorMessage = msg.err.Error()
+			return m, nil
+		}
+		if err := m.state.Apply(msg.move); err != nil {
+			m.errorMessage = err.Error()
+			return m, nil
+		}
+		return m.afterMove()
+
+	case clockTickMsg:
+		if msg.clock != m.clock {
+			return m, nil
+		}
+		if flagged := m.clock.Check(msg.at); flagged != game.Empty {
+			return m.flagged(flagged)
+		}
+		return m, tickClock(m.clock)
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -83,139 +182,304 @@ func (m *GameModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// afterMove ends the game once the last move decided it, otherwise it lets
+// the computer reply when it is its turn.
+func (m *GameModel) afterMove() (tea.Model, tea.Cmd) {
+	if move, ok := m.state.LastMove(); ok {
+		m.history.record(move)
+	}
+	if m.clock != nil {
+		m.clock.Press(time.Now())
+	}
+	if winner := m.state.Winner(); winner != game.Empty {
+		endMessage := constants.WinMsgStyle.Render(winMessage(m.state.Rules(), winner))
+		misere := m.state.Rules().Misere
+		switch {
+		case m.computer != nil && winner == m.human && misere:
+			endMessage = constants.WinMsgStyle.Render(fmt.Sprintf("%s computer completed a line and loses. You win!", m.computer.Name()))
+		case m.computer != nil && winner == m.human:
+			endMessage = constants.WinMsgStyle.Render("You win!")
+		case m.computer != nil && misere:
+			endMessage = constants.LoseMsgStyle.Render("You completed a line and lose!")
+		case m.computer != nil:
+			endMessage = constants.LoseMsgStyle.Render(fmt.Sprintf("%s computer wins!", m.computer.Name()))
+		}
+		return m.finish(winner, endMessage)
+	}
+	if m.state.IsDraw() {
+		endMessage := "It's a draw!"
+		return m.finish(game.Empty, constants.DrawMsgStyle.Render(endMessage))
+	}
+	return m, tea.Batch(m.computerMove(), m.positionChanged())
+}
+
+// requestHints analyses the position shown unless that is done or under way.
+func (m *GameModel) requestHints() tea.Cmd {
+	key := positionKey(m.state)
+	if !m.showHints || m.hints.position == key || m.pending == key {
+		return nil
+	}
+	m.pending = key
+	return m.hinter.analyse(m.state)
+}
+
+// positionChanged hides the hints of the previous position, or analyses the
+// new one in analysis mode.
+func (m *GameModel) positionChanged() tea.Cmd {
+	if !m.analysing {
+		m.showHints = false
+		return nil
+	}
+	return m.requestHints()
+}
+
+// historyLocked explains why the history cannot be used right now.
+func (m *GameModel) historyLocked() string {
+	switch {
+	case m.clock != nil:
+		return "Undo is off in timed games!"
+	case m.computerToMove():
+		return "Wait for the computer's move!"
+	}
+	return ""
+}
+
+// undo takes back the last move, against the computer also its reply.
+func (m *GameModel) undo() (tea.Model, tea.Cmd) {
+	if m.errorMessage = m.historyLocked(); m.errorMessage != "" {
+		return m, nil
+	}
+	if !m.history.undo(m.state) {
+		m.errorMessage = "Nothing to undo!"
+		return m, nil
+	}
+	for m.computerToMove() && m.history.undo(m.state) {
+	}
+	// Undoing the computer's first move as X leaves it to move again
+	return m, tea.Batch(m.computerMove(), m.positionChanged())
+}
+
+// redo plays undone moves again until it is a human's turn.
+func (m *GameModel) redo() (tea.Model, tea.Cmd) {
+	if m.errorMessage = m.historyLocked(); m.errorMessage != "" {
+		return m, nil
+	}
+	if !m.history.redo(m.state) {
+		m.errorMessage = "Nothing to redo!"
+		return m, nil
+	}
+	for m.computerToMove() && m.history.redo(m.state) {
+	}
+	return m, tea.Batch(m.computerMove(), m.positionChanged())
+}
+
+// review steps through the history, the game goes on from the position
+// shown when leaving.
+func (m *GameModel) review(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case constants.Up, constants.Left:
+		m.history.jump(m.state, m.history.ply-1)
+	case constants.Down, constants.Right:
+		m.history.jump(m.state, m.history.ply+1)
+	case constants.Home:
+		m.history.jump(m.state, 0)
+	case constants.End:
+		m.history.jump(m.state, len(m.history.moves))
+	case constants.Tab, constants.Enter:
+		m.reviewing = false
+		return m, m.computerMove()
+	case constants.Hint:
+		m.showHints = !m.showHints || m.analysing
+		return m, m.requestHints()
+	case constants.Analysis:
+		m.analysing = !m.analysing
+		m.showHints = m.analysing
+		return m, m.requestHints()
+	case constants.CtrlC, constants.Esc:
+		return m, tea.Quit
+	}
+	return m, m.positionChanged()
+}
+
+// flagged ends the game lost on time by the flagged side.
+func (m *GameModel) flagged(flagged game.Player) (tea.Model, tea.Cmd) {
+	endMessage := constants.LoseMsgStyle.Render(flagMessage(flagged))
+	if m.computer != nil && flagged == m.human {
+		endMessage = constants.LoseMsgStyle.Render("You ran out of time.")
+	} else if m.computer != nil {
+		endMessage = constants.WinMsgStyle.Render("The computer ran out of time. You win!")
+	}
+	return m.finish(flagged.Opponent(), endMessage)
+}
+
+// finish scores the game and shows the outcome with the offer of a rematch.
+func (m *GameModel) finish(winner game.Player, endMessage string) (tea.Model, tea.Cmd) {
+	m.series.record(winner)
+	endMessage += m.series.rate(winner)
+	endMessage += m.teach(winner)
+	endMessage += archiveGame(m.record(), winner, m.clock)
+	endGameModel := NewEndGameModel(m.width, m.height, endMessage).withRematch(m.series, m.rematch)
+	//sleep for 500 ms for better UX
+	time.Sleep(500 * time.Millisecond)
+	return endGameModel, endGameModel.Init()
+}
+
+// teach lets a learning computer learn from the finished game. The note
+// returned for the end screen is empty unless that failed.
+func (m *GameModel) teach(winner game.Player) string {
+	learner, ok := m.computer.(ai.Learner)
+	if !ok {
+		return ""
+	}
+	err := learner.Learn(m.state.Rules(), m.state.Moves(), winner)
+	if err == nil {
+		err = learner.Save()
+	}
+	if err != nil {
+		return "\n\n" + constants.ErrorStyle.Render(formatErrorMessage("The computer could not learn from this game: "+err.Error()))
+	}
+	return ""
+}
+
+// record describes the game so far in notation.
+func (m *GameModel) record() notation.Record {
+	rec := notation.New(m.state, m.series.name(game.X), m.series.name(game.O), m.started)
+	rec.Mode = archive.ModeHotSeat
+	if m.computer != nil {
+		rec.Mode = archive.ModeComputer
+	}
+	return rec
+}
+
+// rematch starts the next game of the series, the other seat moves first.
+func (m *GameModel) rematch(width, height int) (tea.Model, tea.Cmd) {
+	m.series.next()
+	next := NewGameModel(width, height, m.state.Rules())
+	next.series = m.series
+	if m.clock != nil {
+		next.withClock(m.clock.Control())
+	}
+	if m.computer != nil {
+		next.computer = m.computer
+		next.human = m.human.Opponent()
+	}
+	return next, next.Init()
+}
+
+func (m *GameModel) computerToMove() bool {
+	return m.computer != nil && m.state.Turn() != m.human
+}
+
+// computerMove thinks in the background so the board stays responsive.
+func (m *GameModel) computerMove() tea.Cmd {
+	if !m.computerToMove() || m.state.IsTerminal() {
+		return nil
+	}
+	agent, state := m.computer, m.state.Clone()
+	return func() tea.Msg {
+		move, err := agent.Move(state)
+		return computerMoveMsg{move: move, err: err}
+	}
+}
+
 func (m *GameModel) View() string {
 	return m.renderBoard()
 }
 
 func (m *GameModel) moveCursor(delta int) {
-	// Clear the error message when move is made
+	// Clear the messages when move is made
 	m.errorMessage = ""
+	m.infoMessage = ""
 
+	size := m.state.Size()
 	newCursor := m.cursor + delta
 	// Get the column
-	col := m.cursor % 3
-
-	// Calculate the new row and column
-	newRow, newCol := newCursor/3, newCursor%3
+	col := m.cursor % size
 
 	// Check for boundary conditions
-	if newRow < 0 || newRow >= 3 || newCol < 0 || newCol >= 3 {
+	if newCursor < 0 || newCursor >= size*size {
 		newCursor = m.cursor
 	}
 
 	// Handle wrapping around the edges
-	if (col == 2 && delta == 1) || (col == 0 && delta == -1) {
+	if (col == size-1 && delta == 1) || (col == 0 && delta == -1) {
 		newCursor = m.cursor
 	}
 
 	m.cursor = newCursor
 }
 
-func (m *GameModel) placeMarker() bool {
-	row, col := m.cursor/3, m.cursor%3
-	// Check if the cell is empty
-	if m.board[row][col] != 0 {
-		return false
-	}
-	// Place the marker
-	m.board[row][col] = m.current
-	return true
-}
-
-func (m *GameModel) switchPlayer() {
-	// Switch player if 1 (X) or -1 (O)
-	m.current = -m.current
+func (m *GameModel) placeMarker() error {
+	row, col := m.cursor/m.state.Size(), m.cursor%m.state.Size()
+	// The rules reject occupied cells and pass the turn on success
+	return m.state.Apply(game.Move{Row: row, Col: col})
 }
 
 func (m *GameModel) currentMarker() string {
-	if m.current == 1 {
-		return "X"
-	}
-	return "O"
-}
-
-func (m *GameModel) checkWinner() int {
-	winningLines := [][3][2]int{
-		{{0, 0}, {0, 1}, {0, 2}}, // first row
-		{{1, 0}, {1, 1}, {1, 2}}, // second row
-		{{2, 0}, {2, 1}, {2, 2}}, // third row
-		{{0, 0}, {1, 0}, {2, 0}}, // first column
-		{{0, 1}, {1, 1}, {2, 1}}, // second column
-		{{0, 2}, {1, 2}, {2, 2}}, // third column
-		{{0, 0}, {1, 1}, {2, 2}}, // diagonal top-left to bottom-right
-		{{0, 2}, {1, 1}, {2, 0}}, // diagonal top-right to bottom-left
-	}
-
-	for _, line := range winningLines {
-		a, b, c := line[0], line[1], line[2]
-		// Check if all cells in the line are the same and not empty
-		if m.board[a[0]][a[1]] != 0 &&
-			m.board[a[0]][a[1]] == m.board[b[0]][b[1]] &&
-			m.board[a[0]][a[1]] == m.board[c[0]][c[1]] {
-			// Return the winning marker
-			return m.board[a[0]][a[1]]
-		}
-	}
-
-	return 0
+	return m.state.Turn().String()
 }
 
 func (m *GameModel) renderBoard() string {
-	var cells []string
-	for i := 0; i < 9; i++ {
-		row, col := i/3, i%3
-		cell := m.board[row][col]
-		cellStr := " "
-
-		style := constants.CellStyle
-
-		if i == m.cursor {
-			cellStr = constants.BlinkingStyle.Render(m.currentMarker())
-		} else if cell == 1 {
-			cellStr = "X"
-		} else if cell == -1 {
-			cellStr = "O"
-		}
-
-		// Border styling for the cells because if we set a border for each side it has a margin
-		if col == 0 || col == 2 {
-			style = style.UnsetBorderLeft().UnsetBorderRight().UnsetBorderBottom()
-			if row == 0 {
-				style = style.UnsetBorderStyle()
-			}
-		}
-		if col == 1 {
-			style = style.UnsetBorderBottom()
-			if row == 0 {
-				style = style.UnsetBorderTop()
-			}
-		}
-
-		cells = append(cells, style.Render(cellStr))
+	size := m.state.Size()
+	marker := m.currentMarker()
+	if m.computer != nil {
+		marker = m.human.String()
+	}
+	cursorRow, cursorCol := m.cursor/size, m.cursor%size
+	if m.reviewing {
+		cursorRow, cursorCol = -1, -1
+	}
+	hinted := m.showHints && m.hints.position == positionKey(m.state)
+	board := renderGrid(size, markerCell(m.state, cursorRow, cursorCol, marker))
+	if hinted {
+		board = renderGrid(size, hintCell(m.state, m.hints, cursorRow, cursorCol, marker))
 	}
 
 	currentPlayer := fmt.Sprintf("Current player: %s\n", m.currentMarker())
+	if m.series.games() > 0 {
+		currentPlayer = fmt.Sprintf("Current player: %s (%s)\n", m.currentMarker(), m.series.name(m.state.Turn()))
+	}
+	if m.computerToMove() {
+		currentPlayer = fmt.Sprintf("You are %s, the computer is thinking...\n", m.human)
+	} else if m.computer != nil {
+		currentPlayer = fmt.Sprintf("You are %s, your move\n", m.human)
+	}
+	if m.reviewing {
+		currentPlayer = "Reviewing the game\n"
+	}
+	if m.analysing {
+		currentPlayer = strings.TrimSuffix(currentPlayer, "\n") + " (analysis mode)\n"
+	}
 
 	header := constants.HeaderStyle.Render(currentPlayer)
+	rules := constants.InfoStyle.Render(m.state.Rules().String())
+	if m.series.games() > 0 {
+		rules = constants.InfoStyle.Render(m.state.Rules().String() + "\n" + m.series.String())
+	}
 
 	// Quick help
-	footer := constants.SubtleStyle.Render("arrow keys: move | enter: select | ctrl+c or Esc: quit")
-
-	// Joining all cells horizontally
-	board := lipgloss.JoinVertical(lipgloss.Left,
-		lipgloss.JoinHorizontal(lipgloss.Center, cells[0], cells[1], cells[2]),
-		lipgloss.JoinHorizontal(lipgloss.Left, cells[3], cells[4], cells[5]),
-		lipgloss.JoinHorizontal(lipgloss.Left, cells[6], cells[7], cells[8]))
+	help := "arrow keys: move | enter: select | u: undo | U: redo | tab: history | h: hint | a: analysis | s: save | ctrl+c or Esc: quit"
+	if m.reviewing {
+		help = "arrow keys: step | home/end: first/last move | h: hint | a: analysis | tab or enter: play from here"
+	}
+	footer := constants.SubtleStyle.Render(help)
 
 	errorMsg := ""
 	if m.errorMessage != "" {
 		errorMsg = constants.ErrorStyle.Render(m.errorMessage)
+	} else if m.infoMessage != "" {
+		errorMsg = constants.HighlightStyle.Render(m.infoMessage)
+	} else if hinted {
+		errorMsg = constants.HighlightStyle.Render(hintMessage(m.hints, game.Move{Row: m.cursor / size, Col: m.cursor % size}))
+	} else if m.showHints {
+		errorMsg = constants.HighlightStyle.Render("Analysing...")
 	}
 
 	// Joining all elements vertically
 	view := lipgloss.JoinVertical(lipgloss.Center,
 		header,
-		constants.BoardStyle.Render(board),
+		rules,
+		lipgloss.JoinHorizontal(lipgloss.Center, constants.BoardStyle.Render(board), renderClocks(m.clock), m.history.View(m.reviewing)),
 		errorMsg,
 		footer,
 	)
@@ -0,0 +1,428 @@
+// Package notation reads and writes games as text, so they can be saved,
+// continued, replayed and shared.
+//
+// A game is a list of tags followed by the moves, much like chess PGN:
+//
+//	[Variant "Classic"]
+//	[Size "3"]
+//	[K "3"]
+//	[X "Player 1"]
+//	[O "Player 2"]
+//	[Started "2024-05-01T18:04:05Z"]
+//	[Ended "2024-05-01T18:05:10Z"]
+//	[Result "1-0"]
+//
+//	1. b2 a1 2. c3 a3 3. a2 c1 4. b1 b3 5. c2 1-0
+//
+// Tag values are Go quoted strings and times are RFC 3339. Variant, Size and
+// K are required, the other tags may be left out and unknown tags are
+// ignored. An optional Termination tag tells why a game ended early, for
+// example "time forfeit" or "resignation", and Mode how it was played.
+// Games played under misère rules carry [Misere "true"].
+//
+// A file may hold several games one after another, see ReadAll.
+//
+// The moves name cells by column letter and row number counted from the
+// top, see game.Move. Move numbers count the moves of X and are optional.
+// The result comes last: 1-0 when X won, 0-1 when O won, 1/2-1/2 for a draw
+// and * for a game still in progress.
+package notation
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// Extension is the file extension of saved games.
+const Extension = ".ttt"
+
+// Result is the outcome of a game as written in the notation.
+type Result string
+
+const (
+	XWins   Result = "1-0"
+	OWins   Result = "0-1"
+	Draw    Result = "1/2-1/2"
+	Ongoing Result = "*"
+)
+
+// ErrSyntax is wrapped by every error about malformed notation.
+var ErrSyntax = errors.New("bad notation")
+
+// Decided returns the result of a game won by winner, a draw for game.Empty.
+func Decided(winner game.Player) Result {
+	switch winner {
+	case game.X:
+		return XWins
+	case game.O:
+		return OWins
+	}
+	return Draw
+}
+
+// Winner returns the side that won, game.Empty for draws and open games.
+func (r Result) Winner() game.Player {
+	switch r {
+	case XWins:
+		return game.X
+	case OWins:
+		return game.O
+	}
+	return game.Empty
+}
+
+// Over reports whether the game has ended.
+func (r Result) Over() bool {
+	return r != Ongoing
+}
+
+func parseResult(s string) (Result, bool) {
+	switch r := Result(s); r {
+	case XWins, OWins, Draw, Ongoing:
+		return r, true
+	}
+	return "", false
+}
+
+// Record is one game.
+type Record struct {
+	Rules game.Rules
+	// X and O name the players, empty when unknown
+	X string
+	O string
+	// Started and Ended are zero when unknown
+	Started time.Time
+	Ended   time.Time
+	Moves   []game.Move
+	Result  Result
+	// Termination tells why the game ended before the board decided it
+	Termination string
+	// Mode tells how the game was played, such as "hot-seat"
+	Mode string
+}
+
+// New records the moves played in g so far. The result follows the board,
+// callers set it for games lost on time or by resignation.
+func New(g game.Game, x, o string, started time.Time) Record {
+	r := Record{
+		Rules:   g.Rules(),
+		X:       x,
+		O:       o,
+		Started: started,
+		Moves:   append([]game.Move(nil), g.Moves()...),
+		Result:  Ongoing,
+	}
+	if g.IsTerminal() {
+		r.Result = Decided(g.Winner())
+	}
+	return r
+}
+
+// Player returns the name of the one playing p.
+func (r Record) Player(p game.Player) string {
+	if p == game.O {
+		return r.O
+	}
+	return r.X
+}
+
+// Duration is how long the game took, zero when unknown.
+func (r Record) Duration() time.Duration {
+	if r.Started.IsZero() || r.Ended.IsZero() {
+		return 0
+	}
+	return r.Ended.Sub(r.Started)
+}
+
+// Game replays the moves on a new board.
+func (r Record) Game() (game.Game, error) {
+	return r.Position(len(r.Moves))
+}
+
+// Position replays the first ply moves on a new board.
+func (r Record) Position(ply int) (game.Game, error) {
+	if ply < 0 || ply > len(r.Moves) {
+		return nil, fmt.Errorf("no move %d in a game of %d moves", ply, len(r.Moves))
+	}
+	if err := r.Rules.Validate(); err != nil {
+		return nil, err
+	}
+	g := game.New(r.Rules)
+	for i, m := range r.Moves[:ply] {
+		if err := g.Apply(m); err != nil {
+			return nil, fmt.Errorf("%w: move %d: %v", ErrSyntax, i+1, err)
+		}
+	}
+	return g, nil
+}
+
+// Validate checks that the moves can be played and agree with the result.
+func (r Record) Validate() error {
+	g, err := r.Game()
+	if err != nil {
+		return err
+	}
+	if _, ok := parseResult(string(r.Result)); !ok {
+		return fmt.Errorf("%w: unknown result %q", ErrSyntax, r.Result)
+	}
+	if g.IsTerminal() && r.Result != Decided(g.Winner()) {
+		return fmt.Errorf("%w: the moves end in %s, not %s", ErrSyntax, Decided(g.Winner()), r.Result)
+	}
+	return nil
+}
+
+// Write encodes r in the notation.
+func Write(w io.Writer, r Record) error {
+	var b strings.Builder
+	tag := func(name, value string) {
+		fmt.Fprintf(&b, "[%s %s]\n", name, strconv.Quote(value))
+	}
+	tag("Variant", r.Rules.Variant.String())
+	tag("Size", strconv.Itoa(r.Rules.Size))
+	tag("K", strconv.Itoa(r.Rules.K))
+	if r.Rules.Misere {
+		tag("Misere", "true")
+	}
+	if r.X != "" {
+		tag("X", r.X)
+	}
+	if r.O != "" {
+		tag("O", r.O)
+	}
+	if !r.Started.IsZero() {
+		tag("Started", r.Started.Format(time.RFC3339))
+	}
+	if !r.Ended.IsZero() {
+		tag("Ended", r.Ended.Format(time.RFC3339))
+	}
+	tag("Result", string(r.Result))
+	if r.Termination != "" {
+		tag("Termination", r.Termination)
+	}
+	if r.Mode != "" {
+		tag("Mode", r.Mode)
+	}
+	b.WriteString("\n")
+
+	// Ten moves of each side to a line keep big boards readable
+	for i, m := range r.Moves {
+		switch {
+		case i%2 == 0 && i > 0 && i%20 == 0:
+			b.WriteString("\n")
+		case i > 0:
+			b.WriteString(" ")
+		}
+		if i%2 == 0 {
+			fmt.Fprintf(&b, "%d. ", i/2+1)
+		}
+		b.WriteString(m.String())
+	}
+	if len(r.Moves) > 0 {
+		b.WriteString(" ")
+	}
+	b.WriteString(string(r.Result) + "\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Read decodes one game and checks it with Validate.
+func Read(rd io.Reader) (Record, error) {
+	var r Record
+	var haveRules [3]bool
+	var moves []string
+	ended := false
+
+	scanner := bufio.NewScanner(rd)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "[") {
+			if len(moves) > 0 || ended {
+				return Record{}, fmt.Errorf("%w: line %d: tag after the moves", ErrSyntax, line)
+			}
+			name, value, err := parseTag(text)
+			if err != nil {
+				return Record{}, fmt.Errorf("%w: line %d: %v", ErrSyntax, line, err)
+			}
+			if err := r.set(name, value, &haveRules); err != nil {
+				return Record{}, fmt.Errorf("%w: line %d: %s: %v", ErrSyntax, line, name, err)
+			}
+			continue
+		}
+		for _, token := range strings.Fields(text) {
+			if ended {
+				return Record{}, fmt.Errorf("%w: line %d: %q after the result", ErrSyntax, line, token)
+			}
+			if result, ok := parseResult(token); ok {
+				if r.Result == "" {
+					r.Result = result
+				}
+				if result != r.Result {
+					return Record{}, fmt.Errorf("%w: line %d: result %s does not match the Result tag %s", ErrSyntax, line, result, r.Result)
+				}
+				ended = true
+				continue
+			}
+			if number, ok := strings.CutSuffix(token, "."); ok {
+				if _, err := strconv.Atoi(number); err == nil {
+					continue
+				}
+			}
+			moves = append(moves, token)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Record{}, err
+	}
+	if r.Result == "" {
+		r.Result = Ongoing
+	}
+	if haveRules != [3]bool{true, true, true} {
+		return Record{}, fmt.Errorf("%w: the Variant, Size and K tags are required", ErrSyntax)
+	}
+
+	for _, name := range moves {
+		m, err := game.ParseMove(name)
+		if err != nil {
+			return Record{}, fmt.Errorf("%w: %v", ErrSyntax, err)
+		}
+		r.Moves = append(r.Moves, m)
+	}
+	if err := r.Validate(); err != nil {
+		return Record{}, err
+	}
+	return r, nil
+}
+
+// ReadAll decodes every game of a file. A game ends where the tags of the
+// next one begin. A game that cannot be read, such as one cut short by a
+// crash, is skipped and its error added to bad, so it does not hide the
+// others. err is only set when reading rd fails.
+func ReadAll(rd io.Reader) (records []Record, bad []error, err error) {
+	var text strings.Builder
+	inMoves := false
+	n := 0
+	flush := func() {
+		if strings.TrimSpace(text.String()) == "" {
+			return
+		}
+		n++
+		r, err := Read(strings.NewReader(text.String()))
+		if err != nil {
+			bad = append(bad, fmt.Errorf("game %d: %w", n, err))
+		} else {
+			records = append(records, r)
+		}
+		text.Reset()
+	}
+
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		tag := strings.HasPrefix(line, "[")
+		if tag && inMoves {
+			flush()
+			inMoves = false
+		}
+		if line != "" && !tag {
+			inMoves = true
+		}
+		text.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return records, bad, err
+	}
+	flush()
+	return records, bad, nil
+}
+
+// parseTag splits [Name "value"].
+func parseTag(text string) (name, value string, err error) {
+	inner, ok := strings.CutSuffix(text[1:], "]")
+	if !ok {
+		return "", "", errors.New("tag without a closing ]")
+	}
+	name, quoted, ok := strings.Cut(inner, " ")
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("tag %q without a value", inner)
+	}
+	value, err = strconv.Unquote(strings.TrimSpace(quoted))
+	if err != nil {
+		return "", "", fmt.Errorf("tag %s: value is not a quoted string", name)
+	}
+	return name, value, nil
+}
+
+// set stores a tag, haveRules notes which of Variant, Size and K were seen.
+func (r *Record) set(name, value string, haveRules *[3]bool) error {
+	var err error
+	switch name {
+	case "Variant":
+		r.Rules.Variant, err = game.ParseVariant(value)
+		haveRules[0] = true
+	case "Size":
+		r.Rules.Size, err = strconv.Atoi(value)
+		haveRules[1] = true
+	case "K":
+		r.Rules.K, err = strconv.Atoi(value)
+		haveRules[2] = true
+	case "Misere":
+		r.Rules.Misere, err = strconv.ParseBool(value)
+	case "X":
+		r.X = value
+	case "O":
+		r.O = value
+	case "Started":
+		r.Started, err = time.Parse(time.RFC3339, value)
+	case "Ended":
+		r.Ended, err = time.Parse(time.RFC3339, value)
+	case "Result":
+		var ok bool
+		if r.Result, ok = parseResult(value); !ok {
+			err = fmt.Errorf("unknown result %q", value)
+		}
+	case "Termination":
+		r.Termination = value
+	case "Mode":
+		r.Mode = value
+	}
+	return err
+}
+
+// Load reads a game from a file.
+func Load(path string) (Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Record{}, err
+	}
+	defer f.Close()
+	r, err := Read(f)
+	if err != nil {
+		return Record{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Save writes a game to a file, replacing it.
+func Save(path string, r Record) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
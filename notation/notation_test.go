@@ -0,0 +1,159 @@
+package notation
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game/gametest"
+)
+
+// rowByRow returns the first n cells of a board, row after row.
+func rowByRow(size, n int) []game.Move {
+	ms := make([]game.Move, n)
+	for i := range ms {
+		ms[i] = game.Move{Row: i / size, Col: i % size}
+	}
+	return ms
+}
+
+func TestWriteRead(t *testing.T) {
+	started := time.Date(2024, 5, 1, 18, 4, 5, 0, time.UTC)
+	want := Record{
+		Rules:       game.Rules{Variant: game.Classic, Size: 15, K: 5, Misere: true},
+		X:           "Ada \"the\" Player",
+		O:           "Bob",
+		Started:     started,
+		Ended:       started.Add(95 * time.Second),
+		Moves:       rowByRow(15, 45),
+		Result:      OWins,
+		Termination: "time forfeit",
+		Mode:        "hot-seat",
+	}
+
+	var b bytes.Buffer
+	if err := Write(&b, want); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	text := b.String()
+	for _, tag := range []string{`[X "Ada \"the\" Player"]`, `[Mode "hot-seat"]`, `[Termination "time forfeit"]`, `[Misere "true"]`} {
+		if !strings.Contains(text, tag) {
+			t.Errorf("Write() is missing %s:\n%s", tag, text)
+		}
+	}
+	// 45 moves at twenty to a line
+	if lines := strings.Count(text[strings.Index(text, "1. "):], "\n"); lines != 3 {
+		t.Errorf("Write() spread the moves over %d lines, want 3:\n%s", lines, text)
+	}
+
+	got, err := Read(&b)
+	if err != nil {
+		t.Fatalf("Read() error = %v\n%s", err, text)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	want := []Record{
+		{Rules: game.Standard(), X: "Ada", Moves: gametest.Moves(t, "b2", "a1", "c1", "a3", "a2", "c2", "b3", "b1", "c3"), Result: Draw},
+		{Rules: game.Rules{Variant: game.Classic, Size: 4, K: 3}, Moves: gametest.Moves(t, "a1", "d4"), Result: Ongoing, Mode: "lobby"},
+		{Rules: game.Standard(), Moves: gametest.Moves(t, "a1", "b1", "a2", "b2", "a3"), Result: XWins},
+		// Completing the column loses under misère
+		{Rules: game.Rules{Variant: game.Classic, Size: 3, K: 3, Misere: true}, Moves: gametest.Moves(t, "a1", "b1", "a2", "b2", "a3"), Result: OWins},
+	}
+	var b bytes.Buffer
+	for _, r := range want {
+		if err := Write(&b, r); err != nil {
+			t.Fatal(err)
+		}
+		b.WriteString("\n")
+	}
+
+	got, bad, err := ReadAll(&b)
+	if err != nil || len(bad) > 0 {
+		t.Fatalf("ReadAll() error = %v, bad = %v", err, bad)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadAll() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadAllSkipsDamaged(t *testing.T) {
+	const good = "[Variant \"Classic\"]\n[Size \"3\"]\n[K \"3\"]\n\n1. b2 a1 *\n"
+	text := good +
+		// An illegal game
+		"[Variant \"Classic\"]\n[Size \"3\"]\n[K \"3\"]\n\n1. b2 b2 *\n" +
+		good +
+		// Cut short while its tags were written
+		"[Variant \"Classic\"]\n[Size \"3"
+
+	got, bad, err := ReadAll(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("ReadAll() read %d games, want the 2 good ones", len(got))
+	}
+	if len(bad) != 2 || !strings.Contains(bad[0].Error(), "game 2") || !strings.Contains(bad[1].Error(), "game 4") {
+		t.Fatalf("ReadAll() bad = %v, want games 2 and 4", bad)
+	}
+	for _, err := range bad {
+		if !errors.Is(err, ErrSyntax) {
+			t.Errorf("bad game error = %v, want ErrSyntax", err)
+		}
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	const tags = "[Variant \"Classic\"]\n[Size \"3\"]\n[K \"3\"]\n"
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"tag after the moves", tags + "\n1. b2 a1\n[X \"Ada\"]\n"},
+		{"tag after the result", tags + "\n1. b2 *\n[X \"Ada\"]\n"},
+		{"result tag against the board", tags + "[Result \"1-0\"]\n\n1. a1 b1 2. a2 b2 3. c3 b3 1-0\n"},
+		{"result against the result tag", tags + "[Result \"1-0\"]\n\n1. b2 0-1\n"},
+		{"result against the board", tags + "\n1. a1 b1 2. a2 b2 3. a3 1/2-1/2\n"},
+		{"occupied cell", tags + "\n1. b2 b2 *\n"},
+		{"out of bounds", tags + "\n1. b2 d4 *\n"},
+		{"not a cell", tags + "\n1. b2 zz *\n"},
+		{"move after the end", tags + "\n1. a1 b1 2. a2 b2 3. a3 c3 1-0\n"},
+		{"missing rules", "[Variant \"Classic\"]\n\n1. b2 *\n"},
+		{"bad size", "[Variant \"Classic\"]\n[Size \"three\"]\n[K \"3\"]\n\n*\n"},
+		{"bad misere", tags + "[Misere \"maybe\"]\n\n*\n"},
+		{"result ignoring misere", tags + "[Misere \"true\"]\n\n1. a1 b1 2. a2 b2 3. a3 1-0\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(strings.NewReader(tt.text)); !errors.Is(err, ErrSyntax) {
+				t.Errorf("Read() error = %v, want ErrSyntax", err)
+			}
+		})
+	}
+}
+
+func TestPosition(t *testing.T) {
+	r := Record{Rules: game.Standard(), Moves: gametest.Moves(t, "b2", "a1", "c3"), Result: Ongoing}
+	for ply := 0; ply <= len(r.Moves); ply++ {
+		g, err := r.Position(ply)
+		if err != nil {
+			t.Fatalf("Position(%d) error = %v", ply, err)
+		}
+		if !slices.Equal(g.Moves(), r.Moves[:ply]) {
+			t.Errorf("Position(%d) played %v", ply, g.Moves())
+		}
+	}
+	for _, ply := range []int{-1, len(r.Moves) + 1} {
+		if _, err := r.Position(ply); err == nil {
+			t.Errorf("Position(%d) of a %d-move game returned no error", ply, len(r.Moves))
+		}
+	}
+}
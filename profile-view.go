@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/profile"
+)
+
+// ProfileModel asks who plays before a game starts. Known profiles are
+// suggested while typing; leaving every name empty plays an unrated game.
+type ProfileModel struct {
+	width        int
+	height       int
+	labels       []string
+	inputs       []textinput.Model
+	focus        int
+	start        func(width, height int, names []string) (tea.Model, tea.Cmd)
+	errorMessage string
+}
+
+// NewProfileModel asks for one name per label and hands them to start, all
+// empty when the players stay anonymous.
+func NewProfileModel(width, height int, labels []string, start func(width, height int, names []string) (tea.Model, tea.Cmd)) ProfileModel {
+	m := ProfileModel{width: width, height: height, labels: labels, start: start}
+
+	book, err := profile.Load()
+	if err != nil {
+		m.errorMessage = formatErrorMessage(err.Error())
+		book = &profile.Book{}
+	}
+	for i := range labels {
+		t := textinput.New()
+		t.Placeholder = "name, empty to play unrated"
+		t.Width = profile.MaxNameLength + 10
+		t.CharLimit = profile.MaxNameLength
+		t.ShowSuggestions = true
+		t.SetSuggestions(book.Names())
+		t.Cursor.Style = constants.FocusedStyle
+		if i < len(selectedNames) {
+			t.SetValue(selectedNames[i])
+		}
+		m.inputs = append(m.inputs, t)
+	}
+	m.focusInput(0)
+	return m
+}
+
+func (m *ProfileModel) focusInput(i int) tea.Cmd {
+	m.focus = i
+	for j := range m.inputs {
+		m.inputs[j].Blur()
+		m.inputs[j].PromptStyle = constants.NoStyle
+		m.inputs[j].TextStyle = constants.NoStyle
+	}
+	m.inputs[i].PromptStyle = constants.FocusedStyle
+	m.inputs[i].TextStyle = constants.FocusedStyle
+	return m.inputs[i].Focus()
+}
+
+func (m ProfileModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m ProfileModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case constants.CtrlC:
+			return m, tea.Quit
+		case constants.Esc:
+			return initialModel(m.width, m.height), nil
+		case constants.Up, constants.ShiftTab:
+			return m, m.focusInput((m.focus + len(m.inputs) - 1) % len(m.inputs))
+		case constants.Down:
+			return m, m.focusInput((m.focus + 1) % len(m.inputs))
+		case constants.Enter:
+			if m.focus < len(m.inputs)-1 {
+				return m, m.focusInput(m.focus + 1)
+			}
+			return m.submit()
+		}
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	}
+
+	// Tab accepts the suggestion in the focused input
+	var cmd tea.Cmd
+	m.inputs[m.focus], cmd = m.inputs[m.focus].Update(msg)
+	return m, cmd
+}
+
+// submit creates the profiles that are new and starts the game.
+func (m ProfileModel) submit() (tea.Model, tea.Cmd) {
+	names := make([]string, len(m.inputs))
+	empty := 0
+	for i, input := range m.inputs {
+		names[i] = strings.TrimSpace(input.Value())
+		if names[i] == "" {
+			empty++
+		} else if err := profile.ValidateName(names[i]); err != nil {
+			m.errorMessage = fmt.Sprintf("%s: %v", m.labels[i], err)
+			return m, nil
+		}
+	}
+	if empty == len(names) {
+		return m.start(m.width, m.height, nil)
+	}
+	if empty > 0 {
+		m.errorMessage = "Name every player or none of them"
+		return m, nil
+	}
+	if len(names) == 2 && strings.EqualFold(names[0], names[1]) {
+		m.errorMessage = "Both players cannot use the same profile"
+		return m, nil
+	}
+
+	book, err := profile.Load()
+	if err == nil {
+		for _, name := range names {
+			if err = book.Add(name); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = book.Save()
+	}
+	if err != nil {
+		m.errorMessage = formatErrorMessage(err.Error())
+		return m, nil
+	}
+	copy(selectedNames[:], names)
+	return m.start(m.width, m.height, names)
+}
+
+func (m ProfileModel) View() string {
+	header := constants.HeaderStyle.Render("Who is playing?\n")
+
+	rows := make([]string, 0, len(m.inputs))
+	for i, input := range m.inputs {
+		rows = append(rows, fmt.Sprintf("%-10s %s", m.labels[i], input.View()))
+	}
+
+	errorMsg := ""
+	if m.errorMessage != "" {
+		errorMsg = constants.TCPErrStyle.Render(m.errorMessage)
+	}
+
+	footer := constants.SubtleStyle.Render("tab: complete | ↑/↓: switch | enter: play | esc: menu")
+
+	view := lipgloss.JoinVertical(lipgloss.Center,
+		header,
+		lipgloss.JoinVertical(lipgloss.Left, rows...),
+		errorMsg,
+		footer,
+	)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, view)
+}
@@ -0,0 +1,214 @@
+// Package profile keeps named players and their Elo ratings in a JSON file
+// next to the game archive.
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/archive"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+const (
+	// InitialRating is the rating of a new profile.
+	InitialRating = 1200
+	// KFactor bounds how far one game moves a rating.
+	KFactor = 32
+	// MaxNameLength bounds profile names, in runes.
+	MaxNameLength = 20
+)
+
+// Profile is a named player.
+type Profile struct {
+	Name   string  `json:"name"`
+	Rating float64 `json:"rating"`
+	Wins   int     `json:"wins"`
+	Draws  int     `json:"draws"`
+	Losses int     `json:"losses"`
+}
+
+// Games is the number of rated games played.
+func (p Profile) Games() int {
+	return p.Wins + p.Draws + p.Losses
+}
+
+// Book holds every profile known on this machine.
+type Book struct {
+	Profiles []Profile `json:"profiles"`
+}
+
+// ValidateName rejects empty, overlong and unprintable names.
+func ValidateName(name string) error {
+	switch {
+	case strings.TrimSpace(name) != name:
+		return errors.New("name cannot start or end with spaces")
+	case name == "":
+		return errors.New("name cannot be empty")
+	case len([]rune(name)) > MaxNameLength:
+		return fmt.Errorf("name cannot be longer than %d characters", MaxNameLength)
+	case strings.IndexFunc(name, func(r rune) bool { return !unicode.IsPrint(r) }) >= 0:
+		return errors.New("name can only hold printable characters")
+	}
+	return nil
+}
+
+// Path returns the file holding the profiles.
+func Path() (string, error) {
+	dir, err := archive.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "profiles.json"), nil
+}
+
+// Load reads every profile. A missing file holds none.
+func Load() (*Book, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Book{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var b Book
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &b, nil
+}
+
+// Save replaces the file with b. The file is renamed into place, so readers
+// never see half of it.
+func (b *Book) Save() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Get returns the profile called name, names compare ignoring case. It
+// reports false when there is none.
+func (b *Book) Get(name string) (Profile, bool) {
+	if i := b.index(name); i >= 0 {
+		return b.Profiles[i], true
+	}
+	return Profile{Name: name, Rating: InitialRating}, false
+}
+
+func (b *Book) index(name string) int {
+	for i, p := range b.Profiles {
+		if strings.EqualFold(p.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Add creates the profile called name unless it exists already.
+func (b *Book) Add(name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if b.index(name) < 0 {
+		b.Profiles = append(b.Profiles, Profile{Name: name, Rating: InitialRating})
+	}
+	return nil
+}
+
+// Names lists the profiles by name.
+func (b *Book) Names() []string {
+	names := make([]string, len(b.Profiles))
+	for i, p := range b.Profiles {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// Rate scores a game between the profiles x and o, winner is game.Empty for
+// a draw, and returns how much the rating of x changed, o lost as much.
+// Missing profiles are created. A profile cannot be rated against itself.
+func (b *Book) Rate(x, o string, winner game.Player) (float64, error) {
+	if strings.EqualFold(x, o) {
+		return 0, fmt.Errorf("%s cannot be rated against itself", x)
+	}
+	for _, name := range []string{x, o} {
+		if err := b.Add(name); err != nil {
+			return 0, err
+		}
+	}
+	px, po := &b.Profiles[b.index(x)], &b.Profiles[b.index(o)]
+
+	score := 0.5
+	switch winner {
+	case game.X:
+		score = 1
+		px.Wins++
+		po.Losses++
+	case game.O:
+		score = 0
+		px.Losses++
+		po.Wins++
+	default:
+		px.Draws++
+		po.Draws++
+	}
+	delta := KFactor * (score - Expected(px.Rating, po.Rating))
+	px.Rating += delta
+	po.Rating -= delta
+	return delta, nil
+}
+
+// Expected is the score a player rated a is expected to make against one
+// rated b, between 0 and 1.
+func Expected(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// Leaderboard returns the profiles best rated first.
+func (b *Book) Leaderboard() []Profile {
+	board := append([]Profile(nil), b.Profiles...)
+	sort.SliceStable(board, func(i, j int) bool {
+		return board[i].Rating > board[j].Rating
+	})
+	return board
+}
+
+// RateGame loads the profiles, rates one game and saves them again. It
+// returns both profiles as rated and the change for x like Rate.
+func RateGame(x, o string, winner game.Player) (px, po Profile, delta float64, err error) {
+	b, err := Load()
+	if err != nil {
+		return px, po, 0, err
+	}
+	if delta, err = b.Rate(x, o, winner); err != nil {
+		return px, po, 0, err
+	}
+	px, _ = b.Get(x)
+	po, _ = b.Get(o)
+	return px, po, delta, b.Save()
+}
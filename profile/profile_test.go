@@ -0,0 +1,85 @@
+package profile
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"Ada", true},
+		{"Zoë the Great", true},
+		{strings.Repeat("é", MaxNameLength), true},
+		{"", false},
+		{" Ada", false},
+		{"Ada ", false},
+		{strings.Repeat("é", MaxNameLength+1), false},
+		{"Ada\x1b[2J", false},
+		{"Ada\tLovelace", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateName(tt.name); (err == nil) != tt.valid {
+			t.Errorf("ValidateName(%q) error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestRate(t *testing.T) {
+	var b Book
+	delta, err := b.Rate("Ada", "Bob", game.X)
+	if err != nil {
+		t.Fatalf("Rate() error = %v", err)
+	}
+	// Equal ratings expect half a point
+	if delta != KFactor/2 {
+		t.Errorf("Rate() delta = %v, want %v", delta, KFactor/2)
+	}
+	ada, _ := b.Get("ada")
+	bob, _ := b.Get("Bob")
+	if ada.Rating != InitialRating+KFactor/2 || bob.Rating != InitialRating-KFactor/2 {
+		t.Errorf("ratings = %v, %v after a win", ada.Rating, bob.Rating)
+	}
+	if ada.Wins != 1 || bob.Losses != 1 || ada.Games() != 1 {
+		t.Errorf("Ada %+v, Bob %+v after one win", ada, bob)
+	}
+
+	// A draw costs the favourite points
+	delta, err = b.Rate("Ada", "Bob", game.Empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := KFactor * (0.5 - Expected(ada.Rating, bob.Rating))
+	if math.Abs(delta-want) > 1e-9 || delta >= 0 {
+		t.Errorf("Rate() of a draw delta = %v, want %v", delta, want)
+	}
+
+	if _, err := b.Rate("Ada", "ADA", game.X); err == nil {
+		t.Errorf("Rate() rated a profile against itself")
+	}
+	if _, err := b.Rate("Ada", "Bob\n", game.X); err == nil {
+		t.Errorf("Rate() accepted a bad name")
+	}
+	if board := b.Leaderboard(); len(board) != 2 || board[0].Name != "Ada" {
+		t.Errorf("Leaderboard() = %+v, want Ada first", board)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	if _, _, _, err := RateGame("Ada", "Bob", game.O); err != nil {
+		t.Fatalf("RateGame() error = %v", err)
+	}
+	b, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if bob, ok := b.Get("Bob"); !ok || bob.Wins != 1 || bob.Rating <= InitialRating {
+		t.Errorf("Get(Bob) = %+v, %v after a saved win", bob, ok)
+	}
+}
@@ -0,0 +1,73 @@
+package protocol
+
+import (
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/clock"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// Control is a time control in milliseconds.
+type Control struct {
+	Bank      int64 `json:"bank,omitempty"`
+	Increment int64 `json:"increment,omitempty"`
+	PerMove   int64 `json:"per_move,omitempty"`
+}
+
+// NewControl encodes c, nil for untimed games.
+func NewControl(c clock.Control) *Control {
+	if !c.Enabled() {
+		return nil
+	}
+	return &Control{Bank: c.Bank.Milliseconds(), Increment: c.Increment.Milliseconds(), PerMove: c.PerMove.Milliseconds()}
+}
+
+// Control decodes the time control, untimed when c is nil.
+func (c *Control) Control() clock.Control {
+	if c == nil {
+		return clock.Control{}
+	}
+	return clock.Control{
+		Bank:      time.Duration(c.Bank) * time.Millisecond,
+		Increment: time.Duration(c.Increment) * time.Millisecond,
+		PerMove:   time.Duration(c.PerMove) * time.Millisecond,
+	}
+}
+
+// ClockState is the host's clock: the banks of both sides and the time left
+// for the current move, in milliseconds, and the side that flagged if any.
+type ClockState struct {
+	X       int64  `json:"x"`
+	O       int64  `json:"o"`
+	Move    int64  `json:"move"`
+	Flagged string `json:"flagged,omitempty"`
+}
+
+// NewClock reports the host's clock after ply moves.
+func NewClock(c *clock.Clock, ply int) Message {
+	x, o, move := c.Banks()
+	state := &ClockState{X: x.Milliseconds(), O: o.Milliseconds(), Move: move.Milliseconds()}
+	if flagged := c.Flagged(); flagged != game.Empty {
+		state.Flagged = flagged.String()
+	}
+	return Message{Type: TypeClock, Clock: state, Ply: ply}
+}
+
+// Times returns the banks and the move time of a clock message.
+func (m Message) Times() (x, o, move time.Duration) {
+	return time.Duration(m.Clock.X) * time.Millisecond,
+		time.Duration(m.Clock.O) * time.Millisecond,
+		time.Duration(m.Clock.Move) * time.Millisecond
+}
+
+func (m Message) validateClock() error {
+	if m.Clock == nil || m.Ply < 0 {
+		return malformed("clock without times")
+	}
+	if m.Clock.Flagged != "" {
+		if _, err := game.ParsePlayer(m.Clock.Flagged); err != nil {
+			return malformed("clock with bad flag: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBusy is returned by Receive while another Receive is waiting.
+var ErrBusy = errors.New("already receiving")
+
+// Conn frames messages on a network connection. Send may be called from
+// several goroutines. Only one Receive waits at a time, the others return
+// ErrBusy at once, so callers may ask for the next message freely without
+// messages being read out of order.
+type Conn struct {
+	conn      net.Conn
+	scanner   *bufio.Scanner
+	mu        sync.Mutex
+	receiving atomic.Bool
+}
+
+// NewConn wraps an established connection.
+func NewConn(conn net.Conn) *Conn {
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 512), MaxMessageSize)
+	return &Conn{conn: conn, scanner: scanner}
+}
+
+// Send validates and writes one message.
+func (c *Conn) Send(m Message) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.conn.Write(append(data, '\n'))
+	return err
+}
+
+// Receive blocks until the next message arrives. Messages that are too long,
+// are not valid JSON, carry unknown fields or fail Validate are reported as
+// ErrMalformed.
+func (c *Conn) Receive() (Message, error) {
+	if !c.receiving.CompareAndSwap(false, true) {
+		return Message{}, ErrBusy
+	}
+	defer c.receiving.Store(false)
+
+	if !c.scanner.Scan() {
+		err := c.scanner.Err()
+		if errors.Is(err, bufio.ErrTooLong) {
+			return Message{}, fmt.Errorf("%w: longer than %d bytes", ErrMalformed, MaxMessageSize)
+		}
+		if err == nil {
+			err = io.EOF
+		}
+		return Message{}, err
+	}
+
+	var m Message
+	decoder := json.NewDecoder(bytes.NewReader(c.scanner.Bytes()))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&m); err != nil {
+		return Message{}, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if decoder.More() {
+		return Message{}, fmt.Errorf("%w: trailing data", ErrMalformed)
+	}
+	return m, m.Validate()
+}
+
+// Close closes the underlying connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// SetDeadline bounds the time Send and Receive may block.
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// SetWriteDeadline bounds the time Send may block.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// RemoteAddr returns the address of the peer.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
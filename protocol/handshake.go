@@ -0,0 +1,121 @@
+package protocol
+
+import (
+	"fmt"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/clock"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// Offer is the game a host's hello proposes.
+type Offer struct {
+	Session string
+	Rules   game.Rules
+	// Guest is the side the guest plays
+	Guest   game.Player
+	Control clock.Control
+	// Name is the host's profile name
+	Name string
+}
+
+// Host greets a guest that just connected: it announces the offer, then
+// waits for the guest's hello and returns the guest's name.
+func Host(c *Conn, o Offer) (string, error) {
+	reply, err := Greet(c, hostHello(o))
+	return reply.Name, err
+}
+
+// Greet sends the host's hello to whoever connected and returns their hello,
+// which tells a guest joining or resuming from a spectator.
+func Greet(c *Conn, hello Message) (Message, error) {
+	if err := c.Send(hello); err != nil {
+		return Message{}, err
+	}
+
+	reply, err := c.expect(TypeHello)
+	if err != nil {
+		return reply, err
+	}
+	return reply, c.checkVersion(reply)
+}
+
+// Join answers the host's greeting under the guest's name and returns the
+// host's offer.
+func Join(c *Conn, name string) (Offer, error) {
+	hello, err := c.expectHost()
+	if err != nil {
+		return Offer{}, err
+	}
+
+	// Validate has already checked the rules, the player and the control
+	rules, _ := hello.Rules()
+	player, _ := game.ParsePlayer(hello.Player)
+	o := Offer{Session: hello.Session, Rules: rules, Guest: player, Control: hello.Control.Control(), Name: hello.Name}
+	if err := c.Send(Message{Type: TypeHello, Version: Version, Session: hello.Session, Name: name}); err != nil {
+		return Offer{}, err
+	}
+	return o, nil
+}
+
+// HostHello is the hello a host sends while g is being played: besides the
+// offer it carries the position for resuming.
+func HostHello(o Offer, g game.Game) Message {
+	hello := hostHello(o)
+	hello.Ply, hello.Hash = g.Plies(), Hash(g)
+	return hello
+}
+
+func hostHello(o Offer) Message {
+	return Message{
+		Type:    TypeHello,
+		Version: Version,
+		Session: o.Session,
+		Variant: o.Rules.Variant.String(),
+		Size:    o.Rules.Size,
+		K:       o.Rules.K,
+		Misere:  o.Rules.Misere,
+		Player:  o.Guest.String(),
+		Control: NewControl(o.Control),
+		Name:    o.Name,
+	}
+}
+
+// expectHost receives the host's hello and checks its version and rules.
+func (c *Conn) expectHost() (Message, error) {
+	hello, err := c.expect(TypeHello)
+	if err != nil {
+		return hello, err
+	}
+	if err := c.checkVersion(hello); err != nil {
+		return hello, err
+	}
+	if hello.Variant == "" {
+		return hello, fmt.Errorf("%w: host hello without rules", ErrMalformed)
+	}
+	return hello, nil
+}
+
+// expect receives the next message and fails unless it has type t. An error
+// message from the peer is returned as an error.
+func (c *Conn) expect(t Type) (Message, error) {
+	m, err := c.Receive()
+	if err != nil {
+		return m, err
+	}
+	if m.Type == TypeError {
+		return m, fmt.Errorf("peer refused: %s", m.Text)
+	}
+	if m.Type != t {
+		return m, fmt.Errorf("%w: got %s, want %s", ErrUnexpected, m.Type, t)
+	}
+	return m, nil
+}
+
+// checkVersion refuses a peer speaking another protocol version and tells it why.
+func (c *Conn) checkVersion(hello Message) error {
+	if hello.Version == Version {
+		return nil
+	}
+	_ = c.Send(NewError(CodeVersion, "protocol version %d is not supported, want %d", hello.Version, Version))
+	return fmt.Errorf("%w: peer speaks %d, we speak %d", ErrVersionMismatch, hello.Version, Version)
+}
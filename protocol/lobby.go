@@ -0,0 +1,96 @@
+package protocol
+
+import (
+	"strings"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// Lobby actions. Clients list the open rooms, create a room, join one by its
+// code, ask to be matched with anyone playing the same rules, or rejoin the
+// peer of a dropped game. The server answers with the rooms, the code of a
+// created room and, once two clients are paired, matched. From then on the
+// server relays every message between the two unchanged.
+const (
+	ActionList    = "list"
+	ActionCreate  = "create"
+	ActionJoin    = "join"
+	ActionQuick   = "quick"
+	ActionRejoin  = "rejoin"
+	ActionRooms   = "rooms"
+	ActionRoom    = "room"
+	ActionMatched = "matched"
+)
+
+const (
+	// MaxRoomName bounds the name of a room.
+	MaxRoomName = 32
+	// MaxRoomCode bounds room codes.
+	MaxRoomCode = 8
+	// MaxRooms bounds the rooms listed at once.
+	MaxRooms = 20
+)
+
+// Room is an open game waiting in the lobby.
+type Room struct {
+	Code    string `json:"code"`
+	Name    string `json:"name,omitempty"`
+	Variant string `json:"variant"`
+	Size    int    `json:"size"`
+	K       int    `json:"k"`
+	Misere  bool   `json:"misere,omitempty"`
+}
+
+// Rules returns the rules the room is played under.
+func (r Room) Rules() (game.Rules, error) {
+	return Message{Variant: r.Variant, Size: r.Size, K: r.K, Misere: r.Misere}.Rules()
+}
+
+// NewRoom describes a room played under rules.
+func NewRoom(code, name string, rules game.Rules) Room {
+	return Room{Code: code, Name: name, Variant: rules.Variant.String(), Size: rules.Size, K: rules.K, Misere: rules.Misere}
+}
+
+// NewLobby builds a lobby request. Create and quick carry the rules of the
+// game, the other actions ignore them.
+func NewLobby(action string, rules game.Rules) Message {
+	return Message{Type: TypeLobby, Action: action, Variant: rules.Variant.String(), Size: rules.Size, K: rules.K, Misere: rules.Misere}
+}
+
+// NormalizeCode makes room codes case-insensitive.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (m Message) validateLobby() error {
+	switch m.Action {
+	case ActionList, ActionMatched:
+	case ActionCreate, ActionQuick:
+		if _, err := m.Rules(); err != nil {
+			return malformed("%s with bad rules: %v", m.Action, err)
+		}
+		if len(m.Name) > MaxRoomName || !printable(m.Name) {
+			return malformed("room name must be printable and at most %d bytes", MaxRoomName)
+		}
+	case ActionJoin, ActionRoom:
+		if m.Room == "" || len(m.Room) > MaxRoomCode || !printable(m.Room) {
+			return malformed("%s needs a printable room code of 1..%d bytes", m.Action, MaxRoomCode)
+		}
+	case ActionRejoin:
+		if m.Room == "" || len(m.Room) > MaxRoomCode || !printable(m.Room) || m.Session == "" || len(m.Session) > MaxSessionLength {
+			return malformed("rejoin needs a room code and a session")
+		}
+	case ActionRooms:
+		if len(m.Rooms) > MaxRooms {
+			return malformed("more than %d rooms", MaxRooms)
+		}
+		for _, room := range m.Rooms {
+			if _, err := room.Rules(); err != nil || room.Code == "" || !printable(room.Code) || len(room.Name) > MaxRoomName || !printable(room.Name) {
+				return malformed("bad room %q", room.Code)
+			}
+		}
+	default:
+		return malformed("unknown lobby action %q", m.Action)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+// Package protocol defines the messages exchanged by two networked games.
+//
+// Every message is a single JSON object on its own line. A session starts
+// with a hello from each side: the host announces the protocol version, the
+// session ID, the rules and the side the guest plays, and the guest answers
+// with its own version. Afterwards either side may send any of the other
+// message types.
+//
+// Spectators connect like a guest but answer the host's hello with watch set.
+// They get a snapshot of the moves played so far and then every move, and
+// the players are told how many spectators are watching.
+//
+// Players who cannot reach each other directly meet through a lobby server
+// first, see lobby.go; once matched, the lobby relays the messages above.
+//
+// Both hellos may carry the name of the sender's profile, so each side can
+// rate the game under the other's name.
+//
+// A dropped connection can be resumed: both sides say hello again with the
+// session ID, the number of moves they have played and a hash of their
+// position, and the side that is ahead resends the moves the other missed.
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/profile"
+)
+
+// Version is the protocol revision spoken by this build. Peers with a
+// different version are refused during the handshake.
+const Version = 1
+
+const (
+	// MaxMessageSize bounds a single encoded message, newline included.
+	MaxMessageSize = 4096
+	// MaxChatLength bounds the text of a chat message.
+	MaxChatLength = 500
+	// MaxSessionLength bounds session IDs and position hashes.
+	MaxSessionLength = 64
+)
+
+// Type tells what a message means.
+type Type string
+
+const (
+	TypeHello   Type = "hello"
+	TypeMove    Type = "move"
+	TypeResign  Type = "resign"
+	TypeRematch Type = "rematch"
+	TypeChat    Type = "chat"
+	TypeError   Type = "error"
+	TypeLobby   Type = "lobby"
+	// sent by a host that has spectators
+	TypeSnapshot   Type = "snapshot"
+	TypeSpectators Type = "spectators"
+	// sent by the host of a timed game
+	TypeClock Type = "clock"
+)
+
+// Answers to a rematch request. A rematch message without an answer asks
+// for a rematch.
+const (
+	AnswerAccept  = "accept"
+	AnswerDecline = "decline"
+)
+
+// Error codes carried by error messages.
+const (
+	CodeVersion     = "version"
+	CodeMalformed   = "malformed"
+	CodeUnexpected  = "unexpected"
+	CodeIllegalMove = "illegal-move"
+	CodeSession     = "session"
+	CodeDiverged    = "diverged"
+)
+
+var (
+	ErrMalformed       = errors.New("malformed message")
+	ErrVersionMismatch = errors.New("protocol version mismatch")
+	ErrUnexpected      = errors.New("unexpected message")
+	// ErrIllegalMove reports a move the receiver's own position does not allow
+	ErrIllegalMove = errors.New("illegal move")
+)
+
+// Cell is a zero-based board coordinate.
+type Cell struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+// Message is the envelope of every message. Only the fields of its type are
+// set; Validate enforces which ones.
+type Message struct {
+	Type Type `json:"type"`
+
+	// hello
+	Version int    `json:"version,omitempty"`
+	Variant string `json:"variant,omitempty"`
+	Size    int    `json:"size,omitempty"`
+	K       int    `json:"k,omitempty"`
+	Misere  bool   `json:"misere,omitempty"`
+
+	// hello: the game being played or resumed and, when resuming, the hash
+	// of the sender's position
+	Session string `json:"session,omitempty"`
+	Hash    string `json:"hash,omitempty"`
+
+	// hello from the host: the side of the guest; move: the side moving;
+	// resign relayed to spectators: the side resigning
+	Player string `json:"player,omitempty"`
+	// hello from a spectator
+	Watch bool `json:"watch,omitempty"`
+	// hello from the host of a timed game
+	Control *Control `json:"control,omitempty"`
+	// clock: the host's clock once Ply moves have been played
+	Clock *ClockState `json:"clock,omitempty"`
+
+	// move: the cell and the one-based number of the move in the game;
+	// hello: the number of moves the sender has played
+	Cell *Cell `json:"cell,omitempty"`
+	Ply  int   `json:"ply,omitempty"`
+
+	// rematch
+	Answer string `json:"answer,omitempty"`
+
+	// chat and error
+	Text string `json:"text,omitempty"`
+	// error
+	Code string `json:"code,omitempty"`
+
+	// snapshot: every move so far as row*size+col; spectators: how many
+	Moves []int `json:"moves,omitempty"`
+	Count int   `json:"count,omitempty"`
+
+	// lobby: what to do, the room code and name, the open rooms and, once
+	// matched, whether the receiver hosts the game; hello: the profile name
+	// of the sender
+	Action string `json:"action,omitempty"`
+	Room   string `json:"room,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Rooms  []Room `json:"rooms,omitempty"`
+	Host   bool   `json:"host,omitempty"`
+}
+
+// Validate checks that the message carries exactly what its type needs.
+func (m Message) Validate() error {
+	switch m.Type {
+	case TypeHello:
+		if m.Version <= 0 {
+			return malformed("hello without a version")
+		}
+		if len(m.Session) > MaxSessionLength || len(m.Hash) > MaxSessionLength || m.Ply < 0 {
+			return malformed("hello with bad session")
+		}
+		if m.Variant != "" {
+			if _, err := m.Rules(); err != nil {
+				return malformed("hello with bad rules: %v", err)
+			}
+			if _, err := game.ParsePlayer(m.Player); err != nil {
+				return malformed("hello with bad player: %v", err)
+			}
+		}
+		if err := m.Control.Control().Validate(); err != nil {
+			return malformed("hello with bad %v", err)
+		}
+		// The name is shown and rated like a local profile's
+		if m.Name != "" {
+			if err := profile.ValidateName(m.Name); err != nil {
+				return malformed("hello with bad player name: %v", err)
+			}
+		}
+	case TypeMove:
+		if m.Cell == nil {
+			return malformed("move without a cell")
+		}
+		if _, err := game.ParsePlayer(m.Player); err != nil {
+			return malformed("move with bad player: %v", err)
+		}
+		if m.Ply <= 0 {
+			return malformed("move without a move number")
+		}
+	case TypeResign:
+		if m.Player != "" {
+			if _, err := game.ParsePlayer(m.Player); err != nil {
+				return malformed("resign with bad player: %v", err)
+			}
+		}
+	case TypeRematch:
+		if m.Answer != "" && m.Answer != AnswerAccept && m.Answer != AnswerDecline {
+			return malformed("rematch with unknown answer %q", m.Answer)
+		}
+	case TypeChat:
+		if m.Text == "" || len(m.Text) > MaxChatLength || !printable(m.Text) {
+			return malformed("chat text must be printable and 1..%d bytes", MaxChatLength)
+		}
+	case TypeError:
+		if m.Code == "" {
+			return malformed("error without a code")
+		}
+	case TypeLobby:
+		return m.validateLobby()
+	case TypeSnapshot:
+		if len(m.Moves) > game.MaxSize*game.MaxSize {
+			return malformed("snapshot with %d moves", len(m.Moves))
+		}
+	case TypeSpectators:
+		if m.Count < 0 {
+			return malformed("negative spectator count")
+		}
+	case TypeClock:
+		return m.validateClock()
+	default:
+		return malformed("unknown type %q", m.Type)
+	}
+	return nil
+}
+
+// Rules returns the rules announced by a host hello or a lobby request.
+func (m Message) Rules() (game.Rules, error) {
+	variant, err := game.ParseVariant(m.Variant)
+	if err != nil {
+		return game.Rules{}, err
+	}
+	rules := game.Rules{Variant: variant, Size: m.Size, K: m.K, Misere: m.Misere}
+	return rules, rules.Validate()
+}
+
+// Move returns the side and cell of a move message.
+func (m Message) Move() (game.Player, game.Move) {
+	player, _ := game.ParsePlayer(m.Player)
+	return player, game.Move{Row: m.Cell.Row, Col: m.Cell.Col}
+}
+
+// NewMove builds the message for the ply-th move of the game.
+func NewMove(player game.Player, move game.Move, ply int) Message {
+	return Message{Type: TypeMove, Player: player.String(), Cell: &Cell{Row: move.Row, Col: move.Col}, Ply: ply}
+}
+
+// NewError builds an error message.
+func NewError(code, format string, args ...any) Message {
+	return Message{Type: TypeError, Code: code, Text: fmt.Sprintf(format, args...)}
+}
+
+func malformed(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrMalformed, fmt.Sprintf(format, args...))
+}
+
+// printable reports whether s holds printable runes only, so text from a
+// peer cannot move the cursor or restyle the terminal showing it.
+func printable(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return !unicode.IsPrint(r) }) < 0
+}
+
+// ApplyMove plays a move message from the peer on the receiver's own,
+// authoritative position. The receiver plays self, so the peer may only move
+// as the other side, on its turn, with the next move number and within the
+// rules of the game. Rejected moves leave g unchanged and wrap ErrIllegalMove.
+func ApplyMove(g game.Game, self game.Player, m Message) (game.Move, error) {
+	player, move := m.Move()
+	if player != self.Opponent() {
+		return move, fmt.Errorf("%w: opponent moved as %s", ErrIllegalMove, player)
+	}
+	if m.Ply != g.Plies()+1 {
+		return move, fmt.Errorf("%w: move number %d, expected %d", ErrIllegalMove, m.Ply, g.Plies()+1)
+	}
+	if err := g.Play(player, move); err != nil {
+		return move, fmt.Errorf("%w: %s at [%d, %d]: %v", ErrIllegalMove, player, move.Row, move.Col, errors.Unwrap(err))
+	}
+	return move, nil
+}
@@ -0,0 +1,317 @@
+package protocol
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/clock"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// pipe returns the two ends of an in-memory connection.
+func pipe(t *testing.T) (*Conn, net.Conn) {
+	t.Helper()
+	a, b := net.Pipe()
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+	return NewConn(a), b
+}
+
+// receiveRaw writes raw to the peer end and receives it as a message.
+func receiveRaw(t *testing.T, raw string) (Message, error) {
+	t.Helper()
+	c, peer := pipe(t)
+	go func() {
+		_, _ = peer.Write([]byte(raw))
+		peer.Close()
+	}()
+	return c.Receive()
+}
+
+func TestSendReceive(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	sender, receiver := NewConn(a), NewConn(b)
+
+	sent := []Message{
+		{Type: TypeHello, Version: Version, Variant: "Classic", Size: 4, K: 3, Player: "O"},
+		NewMove(game.X, game.Move{Row: 1, Col: 2}, 1),
+		{Type: TypeChat, Text: "good luck"},
+		{Type: TypeRematch, Answer: AnswerAccept},
+		{Type: TypeResign},
+		NewError(CodeIllegalMove, "[%d, %d]: %s", 1, 1, "cell is already occupied"),
+	}
+	errs := make(chan error, 1)
+	go func() {
+		for _, m := range sent {
+			if err := sender.Send(m); err != nil {
+				errs <- err
+				return
+			}
+		}
+		errs <- nil
+	}()
+
+	for _, want := range sent {
+		got, err := receiver.Receive()
+		if err != nil {
+			t.Fatalf("Receive() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Receive() = %+v, want %+v", got, want)
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+}
+
+func TestReceiveRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"oversized frame", `{"type":"chat","text":"` + strings.Repeat("a", MaxMessageSize) + "\"}\n"},
+		{"not JSON", "hello\n"},
+		{"unknown field", `{"type":"resign","colour":"red"}` + "\n"},
+		{"trailing data", `{"type":"resign"} {"type":"resign"}` + "\n"},
+		{"unknown type", `{"type":"dance"}` + "\n"},
+		{"move without a cell", `{"type":"move","player":"X","ply":1}` + "\n"},
+		{"move with a bad player", `{"type":"move","player":"Z","cell":{"row":0,"col":0},"ply":1}` + "\n"},
+		{"move without a number", `{"type":"move","player":"X","cell":{"row":0,"col":0}}` + "\n"},
+		{"hello without a version", `{"type":"hello"}` + "\n"},
+		{"hello with bad rules", `{"type":"hello","version":1,"variant":"classic","size":3,"k":4,"player":"O"}` + "\n"},
+		{"hello with a bad player", `{"type":"hello","version":1,"variant":"classic","size":3,"k":3,"player":"Q"}` + "\n"},
+		{"hello with an escape in the name", `{"type":"hello","version":1,"name":"Ada\u001b[2J"}` + "\n"},
+		{"hello with a long name", `{"type":"hello","version":1,"name":"` + strings.Repeat("é", 21) + "\"}\n"},
+		{"hello with a padded name", `{"type":"hello","version":1,"name":" Ada"}` + "\n"},
+		{"empty chat", `{"type":"chat"}` + "\n"},
+		{"chat with escapes", `{"type":"chat","text":"hi\u001b[2J"}` + "\n"},
+		{"chat with a tab", `{"type":"chat","text":"a\tb"}` + "\n"},
+		{"long chat", `{"type":"chat","text":"` + strings.Repeat("a", MaxChatLength+1) + "\"}\n"},
+		{"unknown rematch answer", `{"type":"rematch","answer":"maybe"}` + "\n"},
+		{"error without a code", `{"type":"error","text":"oops"}` + "\n"},
+		{"unknown lobby action", `{"type":"lobby","action":"dance"}` + "\n"},
+		{"room name with escapes", `{"type":"lobby","action":"create","variant":"classic","size":3,"k":3,"name":"\u001b[2J"}` + "\n"},
+		{"room code with a newline", `{"type":"lobby","action":"join","room":"AB\nCD"}` + "\n"},
+		{"listed room with escapes", `{"type":"lobby","action":"rooms","rooms":[{"code":"ABCD","name":"\u001b[31m","variant":"classic","size":3,"k":3}]}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := receiveRaw(t, tt.raw); !errors.Is(err, ErrMalformed) {
+				t.Errorf("Receive() error = %v, want ErrMalformed", err)
+			}
+		})
+	}
+}
+
+func TestSendRejectsInvalid(t *testing.T) {
+	c, _ := pipe(t)
+	if err := c.Send(Message{Type: TypeChat}); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Send() error = %v, want ErrMalformed", err)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	host, guest := pipe(t)
+	offer := Offer{Session: "abc", Rules: game.Rules{Size: 5, K: 4, Misere: true}, Guest: game.O, Control: clock.Control{Bank: time.Minute, Increment: time.Second}, Name: "Ada"}
+
+	type joined struct {
+		offer Offer
+		err   error
+	}
+	done := make(chan joined, 1)
+	go func() {
+		o, err := Join(NewConn(guest), "Bob")
+		done <- joined{o, err}
+	}()
+
+	name, err := Host(host, offer)
+	if err != nil || name != "Bob" {
+		t.Fatalf("Host() = %q, %v, want Bob", name, err)
+	}
+	if j := <-done; j.err != nil || j.offer != offer {
+		t.Errorf("Join() = %+v, %v, want %+v", j.offer, j.err, offer)
+	}
+}
+
+func TestVersionMismatch(t *testing.T) {
+	host, guest := pipe(t)
+	peer := NewConn(guest)
+
+	refusal := make(chan Message, 1)
+	go func() {
+		hello, _ := peer.Receive()
+		_ = peer.Send(Message{Type: TypeHello, Version: Version + 1, Session: hello.Session})
+		m, _ := peer.Receive()
+		refusal <- m
+	}()
+
+	_, err := Host(host, Offer{Session: "abc", Rules: game.Standard(), Guest: game.O})
+	if !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("Host() error = %v, want ErrVersionMismatch", err)
+	}
+	if m := <-refusal; m.Type != TypeError || m.Code != CodeVersion {
+		t.Errorf("peer got %+v, want a %s error", m, CodeVersion)
+	}
+}
+
+// play returns a classic game with moves played in order.
+func play(t *testing.T, moves ...game.Move) game.Game {
+	t.Helper()
+	g := game.New(game.Standard())
+	for _, m := range moves {
+		if err := g.Apply(m); err != nil {
+			t.Fatalf("%v: %v", m, err)
+		}
+	}
+	return g
+}
+
+func TestApplyMove(t *testing.T) {
+	tests := []struct {
+		name   string
+		player game.Player
+		cell   Cell
+		ply    int
+		want   string
+	}{
+		{"legal", game.O, Cell{0, 0}, 2, ""},
+		{"wrong side", game.X, Cell{0, 0}, 2, "opponent moved as X"},
+		{"stale ply", game.O, Cell{0, 0}, 1, "move number 1, expected 2"},
+		{"future ply", game.O, Cell{0, 0}, 3, "move number 3, expected 2"},
+		{"out of bounds", game.O, Cell{3, 0}, 2, game.ErrOutOfBounds.Error()},
+		{"negative cell", game.O, Cell{0, -1}, 2, game.ErrOutOfBounds.Error()},
+		{"occupied", game.O, Cell{1, 1}, 2, game.ErrOccupied.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The receiver plays X and has played the centre
+			g := play(t, game.Move{Row: 1, Col: 1})
+			m := Message{Type: TypeMove, Player: tt.player.String(), Cell: &tt.cell, Ply: tt.ply}
+			_, err := ApplyMove(g, game.X, m)
+
+			if tt.want == "" {
+				if err != nil || g.Plies() != 2 {
+					t.Fatalf("ApplyMove() error = %v, plies = %d, want the move played", err, g.Plies())
+				}
+				return
+			}
+			if !errors.Is(err, ErrIllegalMove) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ApplyMove() error = %v, want ErrIllegalMove with %q", err, tt.want)
+			}
+			if moves := g.Moves(); len(moves) != 1 || g.Turn() != game.O {
+				t.Errorf("ApplyMove() changed the position on a rejected move: %v", moves)
+			}
+		})
+	}
+}
+
+func TestResumeRejectsDivergedHost(t *testing.T) {
+	host, guest := pipe(t)
+	peer := NewConn(guest)
+
+	refusal := make(chan Message, 1)
+	go func() {
+		_, _ = peer.Receive()
+		// Same move number, different position
+		theirs := play(t, game.Move{Row: 1, Col: 1}, game.Move{Row: 2, Col: 2})
+		_ = peer.Send(Message{Type: TypeHello, Version: Version, Session: "abc", Ply: theirs.Plies(), Hash: Hash(theirs)})
+		m, _ := peer.Receive()
+		refusal <- m
+	}()
+
+	err := Resume(host, true, "abc", play(t, game.Move{Row: 1, Col: 1}, game.Move{Row: 0, Col: 0}), game.X)
+	if !errors.Is(err, ErrDiverged) {
+		t.Fatalf("Resume() error = %v, want ErrDiverged", err)
+	}
+	if m := <-refusal; m.Type != TypeError || m.Code != CodeDiverged {
+		t.Errorf("peer got %+v, want a %s error", m, CodeDiverged)
+	}
+}
+
+func TestResumeRejectsDivergedGuest(t *testing.T) {
+	guest, host := pipe(t)
+	peer := NewConn(host)
+
+	refusal := make(chan Message, 1)
+	go func() {
+		theirs := play(t, game.Move{Row: 1, Col: 1}, game.Move{Row: 2, Col: 2})
+		hello := hostHello(Offer{Session: "abc", Rules: game.Standard(), Guest: game.O})
+		hello.Ply, hello.Hash = theirs.Plies(), Hash(theirs)
+		_ = peer.Send(hello)
+		_, _ = peer.Receive()
+		m, _ := peer.Receive()
+		refusal <- m
+	}()
+
+	// The guest is ahead by one move but disagrees on the second
+	ahead := play(t, game.Move{Row: 1, Col: 1}, game.Move{Row: 2, Col: 0}, game.Move{Row: 0, Col: 0})
+	err := Resume(guest, false, "abc", ahead, game.O)
+	if !errors.Is(err, ErrDiverged) {
+		t.Fatalf("Resume() error = %v, want ErrDiverged", err)
+	}
+	if m := <-refusal; m.Type != TypeError || m.Code != CodeDiverged {
+		t.Errorf("peer got %+v, want a %s error", m, CodeDiverged)
+	}
+}
+
+func TestResumeRejectsSession(t *testing.T) {
+	host, guest := pipe(t)
+	peer := NewConn(guest)
+
+	refusal := make(chan Message, 1)
+	go func() {
+		_, _ = peer.Receive()
+		_ = peer.Send(Message{Type: TypeHello, Version: Version, Session: "xyz"})
+		m, _ := peer.Receive()
+		refusal <- m
+	}()
+
+	err := Resume(host, true, "abc", play(t), game.X)
+	if !errors.Is(err, ErrSession) {
+		t.Fatalf("Resume() error = %v, want ErrSession", err)
+	}
+	if m := <-refusal; m.Type != TypeError || m.Code != CodeSession {
+		t.Errorf("peer got %+v, want a %s error", m, CodeSession)
+	}
+}
+
+func TestResumeCatchesUp(t *testing.T) {
+	host, guest := pipe(t)
+	peer := NewConn(guest)
+
+	behind := play(t, game.Move{Row: 1, Col: 1})
+	done := make(chan error, 1)
+	go func() {
+		hello, err := peer.Receive()
+		if err == nil {
+			err = peer.Send(Message{Type: TypeHello, Version: Version, Session: hello.Session, Ply: behind.Plies(), Hash: Hash(behind)})
+		}
+		for err == nil && behind.Plies() < 3 {
+			var m Message
+			if m, err = peer.Receive(); err == nil {
+				err = behind.Play(m.Move())
+			}
+		}
+		done <- err
+	}()
+
+	ahead := play(t, game.Move{Row: 1, Col: 1}, game.Move{Row: 0, Col: 0}, game.Move{Row: 2, Col: 2})
+	if err := Resume(host, true, "abc", ahead, game.X); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("guest catching up: %v", err)
+	}
+	if Hash(behind) != Hash(ahead) {
+		t.Errorf("guest position differs after catching up")
+	}
+}
@@ -0,0 +1,126 @@
+package protocol
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+var (
+	// ErrSession reports a peer resuming a different game.
+	ErrSession = errors.New("unknown session")
+	// ErrDiverged reports peers whose positions no longer agree.
+	ErrDiverged = errors.New("positions have diverged")
+)
+
+// NewSession returns a random session ID.
+func NewSession() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Hash fingerprints a position: the rules, every cell and the side to move.
+func Hash(g game.Game) string {
+	h := fnv.New64a()
+	rules := g.Rules()
+	fmt.Fprintf(h, "%d/%d/%d/%d:", rules.Variant, rules.Size, rules.K, g.Turn())
+	for row := 0; row < g.Size(); row++ {
+		for col := 0; col < g.Size(); col++ {
+			fmt.Fprintf(h, "%d,", g.At(row, col))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Resume re-establishes a session on a new connection. Both sides announce
+// how many moves they have played and the hash of their position. The agreed
+// move number is the smaller of the two: the side that is ahead checks that
+// its position at that move matches the peer's hash and resends the moves the
+// peer missed, which the peer then receives like any other move.
+func Resume(c *Conn, host bool, session string, g game.Game, self game.Player) error {
+	if host {
+		theirs, err := Greet(c, HostHello(Offer{Session: session, Rules: g.Rules(), Guest: self.Opponent()}, g))
+		if err != nil {
+			return err
+		}
+		return ResumeHost(c, theirs, session, g)
+	}
+
+	theirs, err := c.expectHost()
+	if err == nil {
+		err = c.checkRules(theirs, g.Rules(), self)
+	}
+	if err != nil {
+		return err
+	}
+	if err := c.checkSession(theirs, session); err != nil {
+		return err
+	}
+	// The host spoke first, answer with our own position
+	mine := Message{Type: TypeHello, Version: Version, Session: session, Ply: g.Plies(), Hash: Hash(g)}
+	if err := c.Send(mine); err != nil {
+		return err
+	}
+	return c.catchUp(g, theirs)
+}
+
+// ResumeHost finishes resuming on the host once Greet has returned the
+// guest's hello.
+func ResumeHost(c *Conn, theirs Message, session string, g game.Game) error {
+	if err := c.checkSession(theirs, session); err != nil {
+		return err
+	}
+	return c.catchUp(g, theirs)
+}
+
+func (c *Conn) checkSession(hello Message, session string) error {
+	if hello.Session != session {
+		_ = c.Send(NewError(CodeSession, "session %q is not being played here", hello.Session))
+		return fmt.Errorf("%w: %q", ErrSession, hello.Session)
+	}
+	return nil
+}
+
+// catchUp compares positions at the agreed move number and resends the moves
+// the peer has not seen.
+func (c *Conn) catchUp(g game.Game, theirs Message) error {
+	moves := g.Moves()
+	if theirs.Ply > len(moves) {
+		// The peer is ahead and will resend, its moves are checked on arrival
+		return nil
+	}
+
+	agreed := game.New(g.Rules())
+	for _, m := range moves[:theirs.Ply] {
+		if err := agreed.Apply(m); err != nil {
+			return err
+		}
+	}
+	if Hash(agreed) != theirs.Hash {
+		_ = c.Send(NewError(CodeDiverged, "positions differ at move %d", theirs.Ply))
+		return fmt.Errorf("%w at move %d", ErrDiverged, theirs.Ply)
+	}
+
+	for ply := theirs.Ply; ply < len(moves); ply++ {
+		if err := c.Send(NewMove(agreed.Turn(), moves[ply], ply+1)); err != nil {
+			return err
+		}
+		_ = agreed.Apply(moves[ply])
+	}
+	return nil
+}
+
+// checkRules refuses to resume a game played under other rules or sides.
+func (c *Conn) checkRules(hello Message, want game.Rules, self game.Player) error {
+	rules, _ := hello.Rules()
+	if rules != want || hello.Player != self.String() {
+		_ = c.Send(NewError(CodeSession, "rules %s for %s do not match %s for %s", rules, hello.Player, want, self))
+		return fmt.Errorf("%w: host plays %s", ErrSession, rules)
+	}
+	return nil
+}
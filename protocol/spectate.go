@@ -0,0 +1,45 @@
+package protocol
+
+import (
+	"fmt"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// Watch answers the host's hello as a spectator and returns the session and
+// the rules. A snapshot of the game follows as the next message.
+func Watch(c *Conn) (string, game.Rules, error) {
+	hello, err := c.expectHost()
+	if err != nil {
+		return "", game.Rules{}, err
+	}
+	rules, _ := hello.Rules()
+	if err := c.Send(Message{Type: TypeHello, Version: Version, Session: hello.Session, Watch: true}); err != nil {
+		return "", game.Rules{}, err
+	}
+	return hello.Session, rules, nil
+}
+
+// NewSnapshot lists the moves of g for a spectator.
+func NewSnapshot(g game.Game) Message {
+	moves := make([]int, 0, g.Plies())
+	for _, m := range g.Moves() {
+		moves = append(moves, m.Row*g.Size()+m.Col)
+	}
+	return Message{Type: TypeSnapshot, Moves: moves}
+}
+
+// Replay rebuilds the game of a snapshot, checking every move against rules.
+func (m Message) Replay(rules game.Rules) (game.Game, error) {
+	g := game.New(rules)
+	for i, cell := range m.Moves {
+		move := game.Move{Row: cell / g.Size(), Col: cell % g.Size()}
+		if cell < 0 || !g.InBounds(move) {
+			return g, fmt.Errorf("%w: snapshot move %d is off the board", ErrMalformed, i+1)
+		}
+		if err := g.Apply(move); err != nil {
+			return g, fmt.Errorf("%w: snapshot move %d: %v", ErrIllegalMove, i+1, err)
+		}
+	}
+	return g, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/protocol"
+)
+
+type reconnectedMsg struct{ conn *protocol.Conn }
+
+type reconnectFailedMsg struct{ err error }
+
+// ReconnectModel waits for a dropped TCP game to come back within
+// reconnectGrace and then hands the resumed game back to TCPmodel.
+type ReconnectModel struct {
+	width    int
+	height   int
+	spinner  spinner.Model
+	game     TCPmodel
+	cause    error
+	ctx      context.Context
+	cancel   context.CancelFunc
+	deadline time.Time
+}
+
+func NewReconnectModel(game TCPmodel, cause error) ReconnectModel {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = constants.FocusedStyle
+
+	// Nobody can move while the game is down
+	if game.clock != nil {
+		game.clock.Stop(time.Now())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), reconnectGrace)
+	return ReconnectModel{
+		width:    game.width,
+		height:   game.height,
+		spinner:  s,
+		game:     game,
+		cause:    cause,
+		ctx:      ctx,
+		cancel:   cancel,
+		deadline: time.Now().Add(reconnectGrace),
+	}
+}
+
+func (m ReconnectModel) Init() tea.Cmd {
+	ctx, session, state, player := m.ctx, m.game.session, m.game.state, m.game.player
+	return tea.Batch(m.spinner.Tick, func() tea.Msg {
+		conn, err := reconnect(ctx, session, state, player)
+		if err != nil {
+			return reconnectFailedMsg{err: err}
+		}
+		return reconnectedMsg{conn: conn}
+	})
+}
+
+func (m ReconnectModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case constants.CtrlC:
+			m.cancel()
+			return m, tea.Quit
+		case constants.Esc:
+			m.cancel()
+			m.game.session.table.close()
+			return NewEndGameModel(m.width, m.height, "Gave up reconnecting."), nil
+		}
+
+	case reconnectedMsg:
+		m.cancel()
+		m.game.conn = msg.conn
+		m.game.infoMessage = "Reconnected, the game goes on."
+		if m.game.clock != nil {
+			m.game.clock.Start(m.game.state.Turn(), time.Now())
+			_ = m.game.sendClock()
+		}
+		// The clock kept ticking here, only listening has to start again
+		return m.game, m.game.listen()
+
+	case reconnectFailedMsg:
+		m.cancel()
+		m.game.session.table.close()
+		return NewEndGameModel(m.width, m.height, formatErrorMessage("Connection lost: "+msg.err.Error())), nil
+
+	case spectatorsMsg:
+		m.game.spectators = msg.count
+		return m, m.game.session.table.watchCount()
+
+	case clockTickMsg:
+		if msg.clock != m.game.clock {
+			return m, nil
+		}
+		return m, tickClock(m.game.clock)
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.game.width = msg.Width
+		m.game.height = msg.Height
+	}
+	return m, nil
+}
+
+func (m ReconnectModel) View() string {
+	header := constants.HeaderStyle.Render(fmt.Sprintf("%s Connection lost, reconnecting…", m.spinner.View()))
+
+	remaining := time.Until(m.deadline).Truncate(time.Second)
+	info := constants.InfoStyle.Render(fmt.Sprintf("%s\n\nResuming after move %d, giving up in %s",
+		formatErrorMessage(m.cause.Error()), m.game.state.Plies(), max(remaining, 0)))
+
+	footer := constants.SubtleStyle.Render("esc: give up | ctrl+c: quit")
+
+	view := lipgloss.JoinVertical(lipgloss.Center, header, info, footer)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, view)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/protocol"
+)
+
+// RematchModel shows the outcome of a TCP game while the connection stays
+// open. Either side may offer another game, which starts with X and O swapped
+// once the other side accepts or offers one too.
+type RematchModel struct {
+	width   int
+	height  int
+	message string
+	// game is the finished game, its connection carries the rematch
+	game TCPmodel
+	// asked is set while our offer waits for an answer, offered while the
+	// opponent's offer waits for ours
+	asked       bool
+	offered     bool
+	gone        bool
+	infoMessage string
+}
+
+func NewRematchModel(game TCPmodel, message string) RematchModel {
+	return RematchModel{
+		width:   game.width,
+		height:  game.height,
+		message: message,
+		game:    game,
+	}
+}
+
+func (m RematchModel) Init() tea.Cmd {
+	return receive(m.game.conn)
+}
+
+func (m RematchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.game.chat.open && !m.gone && msg.String() != constants.CtrlC {
+			var cmd tea.Cmd
+			var err error
+			if m.game, cmd, err = m.game.chatKey(msg); err != nil {
+				return m.hangUp("Your opponent left.")
+			}
+			return m, cmd
+		}
+		switch msg.String() {
+		case constants.Tab:
+			if m.gone {
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.game.chat, cmd = m.game.chat.toggle()
+			return m, cmd
+		case constants.Rematch:
+			if m.gone || m.asked {
+				return m, nil
+			}
+			if m.offered {
+				if err := m.game.conn.Send(protocol.Message{Type: protocol.TypeRematch, Answer: protocol.AnswerAccept}); err != nil {
+					return m.hangUp("Could not accept: " + err.Error())
+				}
+				return m.start()
+			}
+			if err := m.game.conn.Send(protocol.Message{Type: protocol.TypeRematch}); err != nil {
+				return m.hangUp("Could not offer a rematch: " + err.Error())
+			}
+			m.asked = true
+			m.infoMessage = "Waiting for your opponent to answer..."
+		case constants.Decline:
+			if !m.offered {
+				return m, nil
+			}
+			if err := m.game.conn.Send(protocol.Message{Type: protocol.TypeRematch, Answer: protocol.AnswerDecline}); err != nil {
+				return m.hangUp("Could not decline: " + err.Error())
+			}
+			m.offered = false
+			m.infoMessage = "You declined the rematch."
+		case constants.M:
+			m.game.conn.Close()
+			m.game.session.table.close()
+			return initialModel(m.width, m.height), nil
+		case constants.Quit, constants.CtrlC, constants.Esc:
+			m.game.conn.Close()
+			m.game.session.table.close()
+			return m, tea.Quit
+		}
+		return m, nil
+
+	case peerMessage:
+		if msg.conn != m.game.conn {
+			return m, nil
+		}
+		switch msg.msg.Type {
+		case protocol.TypeSpectators:
+			m.game.spectators = msg.msg.Count
+		case protocol.TypeChat:
+			m.game.chat = m.game.chat.add(constants.FocusedStyle.Render(m.game.peerName()+":"), msg.msg.Text)
+		}
+		if msg.msg.Type == protocol.TypeRematch {
+			switch msg.msg.Answer {
+			case "":
+				if m.asked {
+					// Both asked at once, which is as good as accepting
+					return m.start()
+				}
+				m.offered = true
+				m.infoMessage = "Your opponent wants a rematch!"
+			case protocol.AnswerAccept:
+				if m.asked {
+					return m.start()
+				}
+			case protocol.AnswerDecline:
+				m.asked = false
+				m.infoMessage = "Your opponent declined the rematch."
+			}
+		}
+		return m, receive(m.game.conn)
+
+	case spectatorsMsg:
+		m.game.spectators = msg.count
+		return m, m.game.session.table.watchCount()
+
+	case errMsg:
+		if msg.conn != m.game.conn {
+			return m, nil
+		}
+		return m.hangUp("Your opponent left.")
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	}
+	return m, nil
+}
+
+// start begins the next game on the same connection with the sides swapped.
+func (m RematchModel) start() (tea.Model, tea.Cmd) {
+	m.game.series.next()
+	next := newTCPModel(m.width, m.height, m.game.conn, m.game.session, m.game.player.Opponent(), m.game.state.Rules())
+	next.series = m.game.series
+	next.spectators = m.game.spectators
+	next.chat = m.game.chat
+	next.infoMessage = "Rematch! X and O are swapped."
+	next.session.table.sync(next.state, next.player)
+	return next, next.Init()
+}
+
+// hangUp closes the connection, only the way back to the menu is left.
+func (m RematchModel) hangUp(reason string) (tea.Model, tea.Cmd) {
+	m.game.conn.Close()
+	m.game.session.table.close()
+	m.gone = true
+	m.game.chat.open = false
+	m.asked = false
+	m.offered = false
+	m.infoMessage = formatErrorMessage(reason)
+	return m, nil
+}
+
+func (m RematchModel) View() string {
+	help := "Press 'p' to offer a rematch, 'm' to return to menu."
+	switch {
+	case m.gone:
+		help = "Press 'm' to return to menu."
+	case m.offered:
+		help = "Press 'p' to accept, 'd' to decline, 'm' to return to menu."
+	case m.asked:
+		help = "Press 'm' to return to menu."
+	}
+	if !m.gone {
+		help += "\n" + m.game.chat.label()
+	}
+	message := fmt.Sprintf("%s\n\n%s\n\n%s", m.message, m.game.series, help)
+	styledMessage := constants.HighlightStyle.Render(message)
+
+	view := lipgloss.JoinVertical(lipgloss.Center,
+		styledMessage,
+		constants.InfoStyle.Render(m.infoMessage),
+	)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.game.withChat(view))
+}
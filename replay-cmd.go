@@ -0,0 +1,28 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/notation"
+	"github.com/spf13/cobra"
+)
+
+// newReplayCmd steps through a saved game.
+func newReplayCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "replay <file>",
+		Short: "Watch a saved game move by move",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rec, err := notation.Load(args[0])
+			if err != nil {
+				return err
+			}
+			m, err := NewReplayModel(0, 0, rec)
+			if err != nil {
+				return err
+			}
+			_, err = tea.NewProgram(m, tea.WithAltScreen()).Run()
+			return err
+		},
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/notation"
+)
+
+// replaySpeeds are the autoplay delays between moves, slowest first.
+var replaySpeeds = []time.Duration{2 * time.Second, time.Second, 500 * time.Millisecond, 250 * time.Millisecond, 100 * time.Millisecond}
+
+// replayTickMsg carries the autoplay run it belongs to, ticks of a run that
+// was stopped are dropped.
+type replayTickMsg struct{ run int }
+
+// ReplayModel steps through a recorded game.
+type ReplayModel struct {
+	width   int
+	height  int
+	rec     notation.Record
+	state   game.Game
+	history history
+	playing bool
+	run     int
+	// speed indexes replaySpeeds
+	speed int
+	// back returns to where the replay was opened, nil quits instead
+	back func(width, height int) (tea.Model, tea.Cmd)
+}
+
+// NewReplayModel shows rec from its first move.
+func NewReplayModel(width, height int, rec notation.Record) (ReplayModel, error) {
+	if err := rec.Validate(); err != nil {
+		return ReplayModel{}, err
+	}
+	return ReplayModel{
+		width:   width,
+		height:  height,
+		rec:     rec,
+		state:   game.New(rec.Rules),
+		history: history{moves: rec.Moves},
+		speed:   1,
+	}, nil
+}
+
+// withBack lets q and Esc return to the caller instead of quitting.
+func (m ReplayModel) withBack(back func(width, height int) (tea.Model, tea.Cmd)) ReplayModel {
+	m.back = back
+	return m
+}
+
+func (m ReplayModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ReplayModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case constants.Left:
+			m.playing = false
+			m.history.undo(m.state)
+		case constants.Right:
+			m.playing = false
+			m.history.redo(m.state)
+		case constants.Home:
+			m.playing = false
+			m.history.jump(m.state, 0)
+		case constants.End:
+			m.playing = false
+			m.history.jump(m.state, len(m.history.moves))
+		case constants.Space:
+			return m.toggle()
+		case constants.Up, constants.Faster:
+			m.speed = min(m.speed+1, len(replaySpeeds)-1)
+		case constants.Down, constants.Slower:
+			m.speed = max(m.speed-1, 0)
+		case constants.Quit, constants.Esc:
+			if m.back != nil {
+				return m.back(m.width, m.height)
+			}
+			return m, tea.Quit
+		case constants.CtrlC:
+			return m, tea.Quit
+		}
+
+	case replayTickMsg:
+		if msg.run != m.run || !m.playing {
+			return m, nil
+		}
+		if !m.history.redo(m.state) || m.history.ply == len(m.history.moves) {
+			m.playing = false
+			return m, nil
+		}
+		return m, m.tick()
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	}
+	return m, nil
+}
+
+// toggle starts or pauses autoplay, starting over once the end was reached.
+func (m ReplayModel) toggle() (tea.Model, tea.Cmd) {
+	m.playing = !m.playing
+	if !m.playing {
+		return m, nil
+	}
+	if m.history.ply == len(m.history.moves) {
+		m.history.jump(m.state, 0)
+	}
+	m.run++
+	return m, m.tick()
+}
+
+func (m ReplayModel) tick() tea.Cmd {
+	run := m.run
+	return tea.Tick(replaySpeeds[m.speed], func(time.Time) tea.Msg {
+		return replayTickMsg{run: run}
+	})
+}
+
+func (m ReplayModel) View() string {
+	header := constants.HeaderStyle.Render(fmt.Sprintf("Replay: %s (X) vs %s (O)\n",
+		playerName(m.rec, game.X, "X"), playerName(m.rec, game.O, "O")))
+
+	info := m.rec.Rules.String()
+	if !m.rec.Started.IsZero() {
+		info += " | " + m.rec.Started.Local().Format("2006-01-02 15:04")
+	}
+	if m.history.ply == len(m.history.moves) {
+		info += "\n" + describeResult(m.rec)
+	}
+
+	var board string
+	switch s := m.state.(type) {
+	case *game.State:
+		board = renderGrid(s.Size(), highlightCell(s))
+	default:
+		// The last move blinks on nested boards
+		last, played := m.state.LastMove()
+		if !played {
+			last = game.Move{Row: -1, Col: -1}
+		}
+		board = renderPosition(m.state, last.Row, last.Col, m.state.Turn().Opponent().String())
+	}
+
+	autoplay := "space: play"
+	if m.playing {
+		autoplay = "space: pause"
+	}
+	back := "quit"
+	if m.back != nil {
+		back = "back"
+	}
+	footer := constants.SubtleStyle.Render(fmt.Sprintf("←/→: step | home/end: first/last | %s | ↑/↓: speed %s | q, esc: %s",
+		autoplay, replaySpeeds[m.speed], back))
+
+	view := lipgloss.JoinVertical(lipgloss.Center,
+		header,
+		constants.InfoStyle.Render(info),
+		lipgloss.JoinHorizontal(lipgloss.Center, constants.BoardStyle.Render(board), m.history.View(true)),
+		footer,
+	)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, view)
+}
+
+// describeResult tells how a recorded game ended.
+func describeResult(rec notation.Record) string {
+	var result string
+	switch rec.Result {
+	case notation.Ongoing:
+		return "Unfinished"
+	case notation.Draw:
+		result = "Draw"
+	default:
+		winner := rec.Result.Winner()
+		result = fmt.Sprintf("%s wins", playerName(rec, winner, winner.String()))
+	}
+	if rec.Termination != "" {
+		result += " by " + rec.Termination
+	}
+	return result
+}
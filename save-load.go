@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/archive"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/clock"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/notation"
+)
+
+// saveGame writes rec to the working directory. The file is named after the
+// start of the game, so saving again updates the same file.
+func saveGame(rec notation.Record) (string, error) {
+	path := "tictactoe-" + rec.Started.Format("20060102-150405") + notation.Extension
+	if err := notation.Save(path, rec); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// savedMessage reports the outcome of saveGame.
+func savedMessage(path string, err error) string {
+	if err != nil {
+		return formatErrorMessage("Could not save the game: " + err.Error())
+	}
+	return "Saved to " + path
+}
+
+// archiveGame adds a finished game to the local archive. Games the board did
+// not decide were lost on time or by resignation. The note returned for the
+// end screen is empty unless archiving failed.
+func archiveGame(rec notation.Record, winner game.Player, c *clock.Clock) string {
+	rec.Ended = time.Now()
+	if !rec.Result.Over() {
+		rec.Result = notation.Decided(winner)
+		rec.Termination = "resignation"
+		if c != nil && c.Flagged() != game.Empty {
+			rec.Termination = "time forfeit"
+		}
+	}
+	if err := archive.Append(rec); err != nil {
+		return "\n\n" + constants.ErrorStyle.Render(formatErrorMessage("Could not record the game: "+err.Error()))
+	}
+	return ""
+}
+
+// loadGame continues a saved game as a hot-seat game.
+func loadGame(path string) (tea.Model, error) {
+	rec, err := notation.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Result.Over() {
+		return nil, fmt.Errorf("%s: the game is already over (%s)", path, rec.Result)
+	}
+	g, err := rec.Game()
+	if err != nil {
+		return nil, err
+	}
+	names := newSeries(playerName(rec, game.X, "Player 1"), playerName(rec, game.O, "Player 2"))
+
+	switch g := g.(type) {
+	case *game.UltimateState:
+		m := NewUltimateModel(0, 0)
+		m.state, m.series = g, names
+		if !rec.Started.IsZero() {
+			m.started = rec.Started
+		}
+		m.history = history{moves: rec.Moves, ply: len(rec.Moves)}
+		return m, nil
+	case *game.State:
+		m := NewGameModel(0, 0, rec.Rules)
+		m.state, m.series = g, names
+		if !rec.Started.IsZero() {
+			m.started = rec.Started
+		}
+		m.history = history{moves: rec.Moves, ply: len(rec.Moves)}
+		return m, nil
+	}
+	return nil, fmt.Errorf("%s: cannot continue %s games", path, rec.Rules.Variant)
+}
+
+// playerName returns the recorded name of p, or fallback when it is missing.
+func playerName(rec notation.Record, p game.Player, fallback string) string {
+	if name := rec.Player(p); name != "" {
+		return name
+	}
+	return fallback
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/profile"
+)
+
+// series keeps the score of consecutive games between the same two seats.
+// The seats stay with the people while X and O swap after every game.
+type series struct {
+	names [2]string
+	wins  [2]int
+	draws int
+	// xSeat is the seat playing X, and so moving first, in the current game
+	xSeat int
+	// rated series are played between profiles, see rate
+	rated bool
+}
+
+func newSeries(first, second string) *series {
+	return &series{names: [2]string{first, second}}
+}
+
+// seat returns the seat playing p in the current game.
+func (s *series) seat(p game.Player) int {
+	if p == game.X {
+		return s.xSeat
+	}
+	return 1 - s.xSeat
+}
+
+// name tells who plays p in the current game.
+func (s *series) name(p game.Player) string {
+	return s.names[s.seat(p)]
+}
+
+// record counts the current game, winner is game.Empty for a draw.
+func (s *series) record(winner game.Player) {
+	if winner == game.Empty {
+		s.draws++
+		return
+	}
+	s.wins[s.seat(winner)]++
+}
+
+// rate updates the ratings of both profiles after the current game and
+// describes the change for the end screen, nothing for unrated series.
+func (s *series) rate(winner game.Player) string {
+	if !s.rated {
+		return ""
+	}
+	x, o, delta, err := profile.RateGame(s.name(game.X), s.name(game.O), winner)
+	if err != nil {
+		return "\n\n" + constants.ErrorStyle.Render(formatErrorMessage("Could not update the ratings: "+err.Error()))
+	}
+	return fmt.Sprintf("\n\nRatings: %s %.0f (%+.0f), %s %.0f (%+.0f)", x.Name, x.Rating, delta, o.Name, o.Rating, -delta)
+}
+
+// next swaps sides for the following game.
+func (s *series) next() {
+	s.xSeat = 1 - s.xSeat
+}
+
+func (s *series) games() int {
+	return s.wins[0] + s.wins[1] + s.draws
+}
+
+func (s *series) String() string {
+	score := fmt.Sprintf("%s %d : %d %s", s.names[0], s.wins[0], s.wins[1], s.names[1])
+	switch s.draws {
+	case 0:
+		return score
+	case 1:
+		return score + " (1 draw)"
+	}
+	return fmt.Sprintf("%s (%d draws)", score, s.draws)
+}
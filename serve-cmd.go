@@ -0,0 +1,23 @@
+package main
+
+import (
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/lobby"
+	"github.com/spf13/cobra"
+)
+
+// newServeCmd runs a lobby server for players who cannot reach each other
+// directly.
+func newServeCmd() *cobra.Command {
+	var port string
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run a lobby server where players meet",
+		Long:  "Run a lobby server on one port. Players list, create and join rooms there or get matched automatically, and the server relays their games.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return lobby.New().ListenAndServe(":" + port)
+		},
+	}
+	cmd.Flags().StringVarP(&port, "port", "p", "8080", "port to listen on")
+	return cmd
+}
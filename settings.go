@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/ai"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/clock"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// The selected* values are chosen on the command line or in the menu. They
+// outlive the menu so returning from a game keeps them.
+var (
+	selectedVariant    = game.Classic
+	selectedRules      = game.Standard()
+	selectedDifficulty = ai.Medium
+	selectedSide       = game.X
+	selectedControl    clock.Control
+	// selectedNames are the profiles last chosen, the first one is yours
+	selectedNames [2]string
+)
+
+// timeControls are the presets offered in the menu, other controls can be
+// set on the command line.
+var timeControls = []clock.Control{
+	{},
+	{PerMove: 10 * time.Second},
+	{Bank: time.Minute},
+	{Bank: 3 * time.Minute, Increment: 2 * time.Second},
+	{Bank: 5 * time.Minute, Increment: 3 * time.Second},
+	{Bank: 10 * time.Minute, Increment: 5 * time.Second},
+}
+
+// menuRules returns the rules of the selected variant. The classic board
+// size is kept while Ultimate is selected.
+func menuRules() game.Rules {
+	if selectedVariant == game.Ultimate {
+		return game.UltimateRules()
+	}
+	return selectedRules
+}
+
+// setting is a value listed below the menu items and changed with ← / →.
+type setting struct {
+	label  func() string
+	adjust func(delta int)
+}
+
+var settings = []setting{
+	{
+		label: func() string {
+			return fmt.Sprintf("Variant:    < %s >", selectedVariant)
+		},
+		adjust: func(delta int) {
+			selectedVariant = game.Variant(cycle(int(selectedVariant)+delta, 2))
+		},
+	},
+	{
+		label: func() string {
+			return fmt.Sprintf("Board size: < %dx%d >", selectedRules.Size, selectedRules.Size)
+		},
+		adjust: func(delta int) {
+			selectedRules.Size = clamp(selectedRules.Size+delta, game.MinSize, game.MaxSize)
+			// Keep K within the board
+			selectedRules.K = min(selectedRules.K, selectedRules.Size)
+		},
+	},
+	{
+		label: func() string {
+			return fmt.Sprintf("In a row:   < %d >", selectedRules.K)
+		},
+		adjust: func(delta int) {
+			selectedRules.K = clamp(selectedRules.K+delta, game.MinK, selectedRules.Size)
+		},
+	},
+	{
+		label: func() string {
+			if selectedRules.Misere {
+				return "Misère:     < on >"
+			}
+			return "Misère:     < off >"
+		},
+		adjust: func(delta int) {
+			selectedRules.Misere = !selectedRules.Misere
+		},
+	},
+	{
+		label: func() string {
+			return fmt.Sprintf("Computer:   < %s >", selectedDifficulty)
+		},
+		adjust: func(delta int) {
+			// Skip the levels that cannot play on the board chosen
+			for i := 0; i < len(ai.Difficulties); i++ {
+				selectedDifficulty = ai.Difficulties[cycle(int(selectedDifficulty)+delta, len(ai.Difficulties))]
+				if selectedDifficulty.CheckRules(selectedRules) == nil {
+					break
+				}
+			}
+		},
+	},
+	{
+		label: func() string {
+			return fmt.Sprintf("Play as:    < %s >", selectedSide)
+		},
+		adjust: func(delta int) {
+			selectedSide = selectedSide.Opponent()
+		},
+	},
+	{
+		label: func() string {
+			return fmt.Sprintf("Clock:      < %s >", selectedControl)
+		},
+		adjust: func(delta int) {
+			// A control from the command line is not a preset, start from the first one
+			current := 0
+			for i, c := range timeControls {
+				if c == selectedControl {
+					current = i
+				}
+			}
+			selectedControl = timeControls[cycle(current+delta, len(timeControls))]
+		},
+	},
+}
+
+func clamp(value, low, high int) int {
+	return max(low, min(value, high))
+}
+
+// cycle wraps value into 0..n-1.
+func cycle(value, n int) int {
+	return (value%n + n) % n
+}
@@ -1,39 +1,285 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/clock"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/protocol"
 )
 
-func setupConnection(wait bool, ip string, port string) (net.Conn, int, error) {
+const (
+	// handshakeTimeout bounds how long a peer may take to say hello.
+	handshakeTimeout = 10 * time.Second
+	// reconnectGrace is how long a dropped game waits for the peer to return.
+	reconnectGrace = time.Minute
+	// redialDelay spaces out attempts to reach the peer again.
+	redialDelay = time.Second
+)
+
+// peerMessage and errMsg remember the connection they came from, so results
+// of a connection that has since been replaced can be ignored.
+type peerMessage struct {
+	msg  protocol.Message
+	conn *protocol.Conn
+}
+type errMsg struct {
+	err  error
+	conn *protocol.Conn
+}
+
+func (e errMsg) Error() string {
+	return e.err.Error()
+}
+
+// session identifies a networked game and how this end reached its peer, so
+// a dropped connection can be re-established and the game resumed.
+type session struct {
+	id   string
+	host bool
+	ip   string
+	port string
+	// lobby and room are set for games matched by a lobby server
+	lobby string
+	room  string
+	// table is the direct host's listener, it seats spectators as well
+	table *table
+	// control is the host's time control for every game of the session
+	control clock.Control
+	// self and peer are the profile names of both players, peer may be empty
+	self string
+	peer string
+}
+
+// setupConnection hosts or joins a game and completes the handshake. The host
+// decides the rules and plays X; the guest learns them from the host. The
+// host keeps listening for spectators until the table is closed.
+// Cancelling ctx stops waiting at any point and closes the listener.
+func setupConnection(ctx context.Context, wait bool, ip string, port string, rules game.Rules, control clock.Control, name string) (*protocol.Conn, session, game.Player, game.Rules, error) {
+	s := session{host: wait, ip: ip, port: port, control: control, self: name}
 	if wait {
-		ln, err := net.Listen("tcp", ":"+port)
+		s.id = protocol.NewSession()
+		t, err := openTable(ctx, port, protocol.Offer{Session: s.id, Rules: rules, Guest: game.O, Control: control, Name: name})
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to listen on port %v: %w", port, err)
+			return nil, s, 0, rules, err
 		}
-		conn, err := ln.Accept()
+		// The table has greeted the guest already
+		guest, err := t.nextGuest(ctx)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to accept a connection: %w", err)
+			t.close()
+			return nil, s, 0, rules, fmt.Errorf("failed to accept a connection: %w", err)
 		}
-		return conn, constants.PlayerX, nil
+		s.table, s.peer = t, guest.hello.Name
+		t.seat(guest.conn)
+		return guest.conn, s, game.X, rules, nil
 	} else {
-		conn, err := net.Dial("tcp", ip+":"+port)
+		conn, err := dial(ctx, ip, port)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to connect to %v:%v: %w", ip, port, err)
+			return nil, s, 0, rules, err
+		}
+		var offer protocol.Offer
+		c := protocol.NewConn(conn)
+		err = handshake(ctx, c, func(c *protocol.Conn) (err error) {
+			offer, err = protocol.Join(c, name)
+			return err
+		})
+		s.id, s.control, s.peer = offer.Session, offer.Control, offer.Name
+		return c, s, offer.Guest, offer.Rules, err
+	}
+}
+
+// startMatched starts the game on a connection the lobby has just paired.
+// Like in a direct game the host, here the creator of the room, decides the
+// rules and plays X.
+func startMatched(ctx context.Context, c *protocol.Conn, addr string, matched protocol.Message, rules game.Rules, control clock.Control, name string) (session, game.Player, game.Rules, error) {
+	s := session{host: matched.Host, lobby: addr, room: matched.Room, control: control, self: name}
+	if s.host {
+		s.id = protocol.NewSession()
+		err := handshake(ctx, c, func(c *protocol.Conn) (err error) {
+			s.peer, err = protocol.Host(c, protocol.Offer{Session: s.id, Rules: rules, Guest: game.O, Control: control, Name: name})
+			return err
+		})
+		return s, game.X, rules, err
+	}
+	var offer protocol.Offer
+	err := handshake(ctx, c, func(c *protocol.Conn) (err error) {
+		offer, err = protocol.Join(c, name)
+		return err
+	})
+	s.id, s.control, s.peer = offer.Session, offer.Control, offer.Name
+	return s, offer.Guest, offer.Rules, err
+}
+
+// reconnect re-establishes a dropped session and resumes g on it. The host
+// waits at its table and turns away connections for other sessions; the
+// guest keeps dialling. Lobby games ask the lobby to pair them again instead. Both
+// sides give up when ctx ends.
+func reconnect(ctx context.Context, s session, g game.Game, player game.Player) (*protocol.Conn, error) {
+	resume := func(c *protocol.Conn) error {
+		return protocol.Resume(c, s.host, s.id, g, player)
+	}
+	for {
+		var c *protocol.Conn
+		var err error
+		switch {
+		case s.lobby != "":
+			c, err = rejoinLobby(ctx, s)
+		case s.host:
+			var guest guestConn
+			if guest, err = s.table.nextGuest(ctx); err == nil {
+				c = guest.conn
+				resume = func(c *protocol.Conn) error {
+					return protocol.ResumeHost(c, guest.hello, s.id, g)
+				}
+			}
+		default:
+			var conn net.Conn
+			if conn, err = dial(ctx, s.ip, s.port); err == nil {
+				c = protocol.NewConn(conn)
+			}
+		}
+		if err == nil {
+			if err = handshake(ctx, c, resume); err == nil {
+				s.table.seat(c)
+				return c, nil
+			}
+			if errors.Is(err, protocol.ErrDiverged) {
+				return nil, err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("could not resume the game: %w", contextErr(ctx, err))
+		case <-time.After(redialDelay):
+		}
+	}
+}
+
+// watchGame joins the game hosted at ip:port as a spectator.
+func watchGame(ctx context.Context, ip, port string) (*protocol.Conn, game.Rules, error) {
+	conn, err := dial(ctx, ip, port)
+	if err != nil {
+		return nil, game.Rules{}, err
+	}
+	var rules game.Rules
+	c := protocol.NewConn(conn)
+	err = handshake(ctx, c, func(c *protocol.Conn) (err error) {
+		_, rules, err = protocol.Watch(c)
+		return err
+	})
+	return c, rules, err
+}
+
+// dialLobby connects to the lobby server at addr.
+func dialLobby(ctx context.Context, addr string) (*protocol.Conn, error) {
+	ip, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("bad lobby address %q: %w", addr, err)
+	}
+	conn, err := dial(ctx, ip, port)
+	if err != nil {
+		return nil, err
+	}
+	return protocol.NewConn(conn), nil
+}
+
+// rejoinLobby waits in the lobby until the peer of s rejoins as well.
+func rejoinLobby(ctx context.Context, s session) (*protocol.Conn, error) {
+	c, err := dialLobby(ctx, s.lobby)
+	if err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { c.Close() })
+	defer stop()
+
+	request := protocol.Message{Type: protocol.TypeLobby, Action: protocol.ActionRejoin, Room: s.room, Session: s.id, Host: s.host}
+	if err := c.Send(request); err != nil {
+		c.Close()
+		return nil, err
+	}
+	reply, err := c.Receive()
+	switch {
+	case err != nil:
+	case reply.Type == protocol.TypeError:
+		err = fmt.Errorf("lobby refused: %s", reply.Text)
+	case reply.Type != protocol.TypeLobby || reply.Action != protocol.ActionMatched:
+		err = fmt.Errorf("%w: got %s %s from the lobby", protocol.ErrUnexpected, reply.Type, reply.Action)
+	}
+	if err != nil {
+		c.Close()
+		return nil, contextErr(ctx, err)
+	}
+	return c, nil
+}
+
+func dial(ctx context.Context, ip, port string) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %v:%v: %w", ip, port, contextErr(ctx, err))
+	}
+	return conn, nil
+}
+
+// contextErr prefers the reason the context ended over the network error it
+// caused.
+func contextErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// handshake runs greet on the new connection within handshakeTimeout and
+// closes the connection if it fails or ctx is cancelled.
+func handshake(ctx context.Context, c *protocol.Conn, greet func(c *protocol.Conn) error) error {
+	_ = c.SetDeadline(time.Now().Add(handshakeTimeout))
+	stop := context.AfterFunc(ctx, func() { c.Close() })
+	err := greet(c)
+	if !stop() || err != nil {
+		c.Close()
+		return fmt.Errorf("handshake failed: %w", contextErr(ctx, err))
+	}
+	_ = c.SetDeadline(time.Time{})
+	return nil
+}
+
+// localAddresses lists the addresses other machines can use to reach this
+// one, so the host can share them with the guest.
+func localAddresses() []string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	var ips []string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || !ipNet.IP.IsGlobalUnicast() {
+			continue
 		}
-		return conn, constants.PlayerO, nil
+		ips = append(ips, ipNet.IP.String())
 	}
+	return ips
 }
-func createReceiveMove(conn net.Conn) func() tea.Msg {
+
+// receive waits for the next message from the peer. It is issued again after
+// every message; if a receive is already waiting it yields nothing, and the
+// waiting one delivers the message to whichever model is active by then.
+func receive(conn *protocol.Conn) tea.Cmd {
 	return func() tea.Msg {
-		buffer := make([]byte, 1024)
-		len, err := conn.Read(buffer)
+		msg, err := conn.Receive()
+		if errors.Is(err, protocol.ErrBusy) {
+			return nil
+		}
 		if err != nil {
-			return errMsg{err: err}
+			return errMsg{err: err, conn: conn}
 		}
-		return moveMessage{command: string(buffer[:len])}
+		return peerMessage{msg: msg, conn: conn}
 	}
 }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/solver"
+	"github.com/spf13/cobra"
+)
+
+// newSolveCmd prints the value of a position under perfect play.
+func newSolveCmd() *cobra.Command {
+	var file string
+	var budget time.Duration
+	cmd := &cobra.Command{
+		Use:   "solve [move]...",
+		Short: "Compute the value of a position under perfect play",
+		Long: "Solve the position reached by the given moves, or by a saved game with --file followed by the moves, " +
+			"printing whether the side to move wins, draws or loses, in how many plies, the best moves and the outcome of every move.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := solvePosition(menuRules(), file, args)
+			if err != nil {
+				return err
+			}
+			sv, err := solver.New(s.Rules())
+			if err != nil {
+				return err
+			}
+			sv.Budget = budget
+			a, err := sv.Analyze(s)
+			if err != nil {
+				return err
+			}
+			return printAnalysis(os.Stdout, s, a)
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "start from the position of a saved game")
+	cmd.Flags().DurationVar(&budget, "budget", time.Minute, "give up after this long, 0 for no limit")
+	return cmd
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/engine"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/notation"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/solver"
+)
+
+// solvePosition builds the position to solve: the moves of a saved game when
+// path is set, then the moves named on the command line.
+func solvePosition(rules game.Rules, path string, moves []string) (*game.State, error) {
+	s := game.NewState(rules)
+	if path != "" {
+		rec, err := notation.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		g, err := rec.Game()
+		if err != nil {
+			return nil, err
+		}
+		state, ok := g.(*game.State)
+		if !ok {
+			return nil, fmt.Errorf("%s: only the classic variant can be solved", path)
+		}
+		s = state
+	}
+	for _, name := range moves {
+		m, err := game.ParseMove(strings.ToLower(name))
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Apply(m); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return s, nil
+}
+
+// printAnalysis writes the board, its value and the outcome of every move.
+func printAnalysis(w io.Writer, s *game.State, a solver.Analysis) error {
+	fmt.Fprintf(w, "%v after %d moves\n\n%s\n\n", s.Rules(), s.Plies(), engine.Board(s))
+
+	if winner := s.Winner(); winner != game.Empty {
+		fmt.Fprintf(w, "%s has won.\n", winner)
+	} else if s.IsDraw() {
+		fmt.Fprintln(w, "The game is drawn.")
+	} else {
+		fmt.Fprintf(w, "%s to move: %s\n", s.Turn(), a.Outcome)
+		fmt.Fprintf(w, "Best moves: %s\n\n", strings.Join(moveNames(a.Best), " "))
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "MOVE\tOUTCOME")
+		for _, m := range s.LegalMoves() {
+			fmt.Fprintf(tw, "%s\t%s\n", m, a.Moves[m])
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+
+	st := a.Stats
+	_, err := fmt.Fprintf(w, "Nodes: %d, table hits: %d, positions stored: %d, time: %v\n", st.Nodes, st.Hits, st.Positions, st.Elapsed.Round(time.Microsecond))
+	return err
+}
+
+func moveNames(moves []game.Move) []string {
+	names := make([]string, len(moves))
+	for i, m := range moves {
+		names[i] = m.String()
+	}
+	return names
+}
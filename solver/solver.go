@@ -0,0 +1,283 @@
+// Package solver computes the game-theoretic value of positions by searching
+// the whole game tree, as ground truth for the computer players.
+//
+// Positions that are rotations or reflections of each other have the same
+// value, so the transposition table stores each one once, under the
+// smallest of its 8 symmetric forms.
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// ErrBudget is returned when the search runs out of time or positions.
+var ErrBudget = errors.New("position too large to solve within the budget")
+
+// Value is the result of perfect play for the side to move.
+type Value int
+
+const (
+	Loss Value = iota - 1
+	Draw
+	Win
+)
+
+func (v Value) String() string {
+	switch v {
+	case Win:
+		return "win"
+	case Loss:
+		return "loss"
+	}
+	return "draw"
+}
+
+// Outcome is a value and how many plies perfect play takes to reach it. The
+// winner hurries and the loser holds out; a draw lasts until the board is
+// full.
+type Outcome struct {
+	Value Value
+	Plies int
+}
+
+func (o Outcome) String() string {
+	if o.Plies == 1 {
+		return fmt.Sprintf("%s in 1 ply", o.Value)
+	}
+	return fmt.Sprintf("%s in %d plies", o.Value, o.Plies)
+}
+
+// Stats tells how much work a search took.
+type Stats struct {
+	// Nodes is the number of positions searched, Hits how many of them were
+	// answered by the transposition table
+	Nodes int64
+	Hits  int64
+	// Positions is the number of entries in the table
+	Positions int
+	Elapsed   time.Duration
+}
+
+// Analysis is the solution of one position.
+type Analysis struct {
+	Outcome Outcome
+	// Best lists every move reaching Outcome, in row-major order
+	Best []game.Move
+	// Moves holds the outcome of every legal move for the side making it
+	Moves map[game.Move]Outcome
+	Stats Stats
+}
+
+// Solver searches positions of one board size. Its table is kept between
+// searches, so related positions are solved faster the second time.
+type Solver struct {
+	// Budget limits the time of one search; zero means no limit.
+	Budget time.Duration
+	// MaxPositions limits the size of the table; zero means no limit.
+	MaxPositions int
+
+	rules    game.Rules
+	table    map[string]int
+	symmetry [8][]int
+	ctx      context.Context
+	started  time.Time
+	deadline time.Time
+	stats    Stats
+}
+
+// winScore is the score of a win on the spot, scores closer to zero take
+// longer.
+const winScore = 1 << 20
+
+// checkEvery is how many nodes are searched between clock checks.
+const checkEvery = 4096
+
+// New returns a solver for positions under rules, which must be classic.
+func New(rules game.Rules) (*Solver, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	if rules.Variant != game.Classic {
+		return nil, errors.New("only the classic variant can be solved")
+	}
+	return &Solver{rules: rules, table: make(map[string]int), symmetry: game.Symmetries(rules.Size)}, nil
+}
+
+// Rules returns the rules of the positions the solver takes.
+func (sv *Solver) Rules() game.Rules {
+	return sv.rules
+}
+
+// Solve returns the outcome of s for the side to move.
+func (sv *Solver) Solve(s *game.State) (Outcome, Stats, error) {
+	if err := sv.begin(context.Background(), s); err != nil {
+		return Outcome{}, Stats{}, err
+	}
+	score, err := sv.negamax(s.Clone())
+	return sv.outcome(s, score), sv.end(), err
+}
+
+// Analyze solves s and every legal move in it.
+func (sv *Solver) Analyze(s *game.State) (Analysis, error) {
+	return sv.AnalyzeContext(context.Background(), s)
+}
+
+// AnalyzeContext is Analyze giving up with the context's error once ctx is
+// done. What was solved until then stays in the table.
+func (sv *Solver) AnalyzeContext(ctx context.Context, s *game.State) (Analysis, error) {
+	if err := sv.begin(ctx, s); err != nil {
+		return Analysis{}, err
+	}
+	a := Analysis{Moves: make(map[game.Move]Outcome)}
+	pos := s.Clone()
+	best := -2 * winScore
+	for _, m := range pos.LegalMoves() {
+		_ = pos.Apply(m)
+		score, err := sv.negamax(pos)
+		score = -back(score)
+		pos.Undo()
+		if err != nil {
+			return a, err
+		}
+
+		a.Moves[m] = sv.outcome(s, score)
+		switch {
+		case score > best:
+			best, a.Best = score, []game.Move{m}
+		case score == best:
+			a.Best = append(a.Best, m)
+		}
+	}
+	if s.IsTerminal() {
+		best = terminalScore(s)
+	}
+	a.Outcome = sv.outcome(s, best)
+	a.Stats = sv.end()
+	return a, nil
+}
+
+func (sv *Solver) begin(ctx context.Context, s *game.State) error {
+	if s.Rules() != sv.rules {
+		return fmt.Errorf("solver is for %v, position is %v", sv.rules, s.Rules())
+	}
+	sv.ctx = ctx
+	sv.stats = Stats{}
+	sv.started = time.Now()
+	sv.deadline = time.Time{}
+	if sv.Budget > 0 {
+		sv.deadline = time.Now().Add(sv.Budget)
+	}
+	return nil
+}
+
+func (sv *Solver) end() Stats {
+	sv.stats.Elapsed = time.Since(sv.started)
+	sv.stats.Positions = len(sv.table)
+	return sv.stats
+}
+
+// outcome turns a score of the side to move in s into an outcome.
+func (sv *Solver) outcome(s *game.State, score int) Outcome {
+	switch {
+	case score > 0:
+		return Outcome{Value: Win, Plies: winScore - score}
+	case score < 0:
+		return Outcome{Value: Loss, Plies: winScore + score}
+	}
+	return Outcome{Value: Draw, Plies: len(s.LegalMoves())}
+}
+
+// negamax scores pos for the side to move: winScore less the plies to a win,
+// the negative of that for a loss and zero for a draw.
+func (sv *Solver) negamax(pos *game.State) (int, error) {
+	sv.stats.Nodes++
+	if pos.IsTerminal() {
+		return terminalScore(pos), nil
+	}
+	if sv.stats.Nodes%checkEvery == 0 {
+		if err := sv.ctx.Err(); err != nil {
+			return 0, err
+		}
+		if !sv.deadline.IsZero() && time.Now().After(sv.deadline) {
+			return 0, ErrBudget
+		}
+	}
+
+	key := sv.canonical(pos)
+	if score, ok := sv.table[key]; ok {
+		sv.stats.Hits++
+		return score, nil
+	}
+
+	best := -2 * winScore
+	for _, m := range pos.LegalMoves() {
+		_ = pos.Apply(m)
+		score, err := sv.negamax(pos)
+		pos.Undo()
+		if err != nil {
+			return 0, err
+		}
+		if score = -back(score); score > best {
+			best = score
+		}
+		if best == winScore-1 {
+			// Nothing beats winning with this move
+			break
+		}
+	}
+
+	if sv.MaxPositions > 0 && len(sv.table) >= sv.MaxPositions {
+		return 0, ErrBudget
+	}
+	sv.table[key] = best
+	return best, nil
+}
+
+// back moves a score one ply further from the result.
+func back(score int) int {
+	switch {
+	case score > 0:
+		return score - 1
+	case score < 0:
+		return score + 1
+	}
+	return 0
+}
+
+// terminalScore is the score of a finished game for the side to move.
+func terminalScore(pos *game.State) int {
+	switch pos.Winner() {
+	case game.Empty:
+		return 0
+	case pos.Turn():
+		return winScore
+	}
+	return -winScore
+}
+
+// canonical encodes the smallest of the position's 8 symmetric forms. The
+// side to move follows from the number of markers.
+func (sv *Solver) canonical(pos *game.State) string {
+	n := sv.rules.Size
+	cells := make([]byte, n*n)
+	for i := range cells {
+		cells[i] = byte(pos.At(i/n, i%n) + 1)
+	}
+
+	best := make([]byte, n*n)
+	form := make([]byte, n*n)
+	for t, perm := range sv.symmetry {
+		for i, j := range perm {
+			form[i] = cells[j]
+		}
+		if t == 0 || string(form) < string(best) {
+			copy(best, form)
+		}
+	}
+	return string(best)
+}
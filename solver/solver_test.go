@@ -0,0 +1,184 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/ai"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game/gametest"
+)
+
+func newSolver(t *testing.T, rules game.Rules) *Solver {
+	t.Helper()
+	sv, err := New(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sv
+}
+
+func TestSolve(t *testing.T) {
+	misere := game.Rules{Variant: game.Classic, Size: 3, K: 3, Misere: true}
+	tests := []struct {
+		name  string
+		rules game.Rules
+		moves []string
+		want  Outcome
+	}{
+		{"empty board", game.Standard(), nil, Outcome{Draw, 9}},
+		{"win on the spot", game.Standard(), []string{"a1", "b1", "a2", "b2"}, Outcome{Win, 1}},
+		{"centre against an edge", game.Standard(), []string{"b2", "a2"}, Outcome{Win, 5}},
+		{"facing a fork", game.Standard(), []string{"b2", "a2", "a1"}, Outcome{Loss, 4}},
+		{"corner against a centre", game.Standard(), []string{"a1", "b2"}, Outcome{Draw, 7}},
+		{"4x4, 3 in a row", game.Rules{Variant: game.Classic, Size: 4, K: 3}, nil, Outcome{Win, 5}},
+		{"misère empty board", misere, nil, Outcome{Draw, 9}},
+		{"misère corner opening", misere, []string{"a1"}, Outcome{Win, 8}},
+		{"game over", game.Standard(), []string{"a1", "b1", "a2", "b2", "a3"}, Outcome{Loss, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := newSolver(t, tt.rules).Solve(gametest.Position(t, tt.rules, tt.moves...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Solve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSolveLong(t *testing.T) {
+	if testing.Short() {
+		t.Skip("takes seconds")
+	}
+	rules := game.Rules{Variant: game.Classic, Size: 4, K: 4}
+	got, _, err := newSolver(t, rules).Solve(game.NewState(rules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Outcome{Draw, 16}); got != want {
+		t.Errorf("Solve() on %v = %v, want %v", rules, got, want)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	s := gametest.Position(t, game.Standard(), "b2", "a2", "a1")
+	a, err := newSolver(t, game.Standard()).Analyze(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// O must block the diagonal, everything else loses at once
+	if want := []game.Move{{Row: 2, Col: 2}}; !slices.Equal(a.Best, want) {
+		t.Errorf("Best = %v, want %v", a.Best, want)
+	}
+	for m, o := range a.Moves {
+		if m != (game.Move{Row: 2, Col: 2}) && o != (Outcome{Loss, 2}) {
+			t.Errorf("Moves[%v] = %v, want loss in 2 plies", m, o)
+		}
+	}
+	if a.Outcome != (Outcome{Loss, 4}) {
+		t.Errorf("Outcome = %v, want loss in 4 plies", a.Outcome)
+	}
+}
+
+func TestSymmetry(t *testing.T) {
+	sv := newSolver(t, game.Standard())
+	first, _, err := sv.Solve(gametest.Position(t, game.Standard(), "a1", "b1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions := len(sv.table)
+
+	// The same position turned and mirrored
+	for _, moves := range [][]string{{"c1", "b1"}, {"a3", "b3"}, {"c3", "b3"}, {"a1", "a2"}, {"c3", "c2"}} {
+		s := gametest.Position(t, game.Standard(), moves...)
+		got, stats, err := sv.Solve(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != first {
+			t.Errorf("%v: Solve() = %v, want %v", moves, got, first)
+		}
+		if stats.Nodes != 1 || stats.Hits != 1 || stats.Positions != positions {
+			t.Errorf("%v: searched %d nodes with %d hits and grew the table to %d, want one hit on the %d entries", moves, stats.Nodes, stats.Hits, stats.Positions, positions)
+		}
+	}
+}
+
+func TestBudget(t *testing.T) {
+	rules := game.Rules{Variant: game.Classic, Size: 4, K: 4}
+
+	sv := newSolver(t, rules)
+	sv.Budget = time.Nanosecond
+	if _, _, err := sv.Solve(game.NewState(rules)); !errors.Is(err, ErrBudget) {
+		t.Errorf("Solve() with Budget error = %v, want ErrBudget", err)
+	}
+
+	sv = newSolver(t, rules)
+	sv.MaxPositions = 100
+	if _, err := sv.Analyze(game.NewState(rules)); !errors.Is(err, ErrBudget) {
+		t.Errorf("Analyze() with MaxPositions error = %v, want ErrBudget", err)
+	}
+	if len(sv.table) > sv.MaxPositions {
+		t.Errorf("table holds %d positions, more than MaxPositions", len(sv.table))
+	}
+}
+
+func TestAnalyzeContext(t *testing.T) {
+	rules := game.Rules{Variant: game.Classic, Size: 4, K: 4}
+	sv := newSolver(t, rules)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := sv.AnalyzeContext(ctx, game.NewState(rules)); !errors.Is(err, context.Canceled) {
+		t.Errorf("AnalyzeContext() error = %v, want context.Canceled", err)
+	}
+
+	// The solver is still good for the next position
+	s := gametest.Position(t, rules, "a1", "b1", "a2", "b2", "a3", "b3")
+	a, err := sv.Analyze(s)
+	if err != nil || a.Outcome != (Outcome{Win, 1}) {
+		t.Errorf("Analyze() after a cancel = %v, %v, want a win in 1", a.Outcome, err)
+	}
+}
+
+// TestPerfectPlay checks that the Perfect level only ever plays moves the
+// solver counts among the best, in every position of a 3x3 game.
+func TestPerfectPlay(t *testing.T) {
+	for _, rules := range []game.Rules{game.Standard(), {Variant: game.Classic, Size: 3, K: 3, Misere: true}} {
+		sv := newSolver(t, rules)
+		seen := make(map[string]bool)
+		var walk func(s *game.State)
+		walk = func(s *game.State) {
+			key := sv.canonical(s)
+			if s.IsTerminal() || seen[key] {
+				return
+			}
+			seen[key] = true
+
+			a, err := sv.Analyze(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for seed := int64(0); seed < 3; seed++ {
+				m, err := ai.New(ai.Perfect, seed).Move(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Contains(a.Best, m) {
+					t.Fatalf("%v after %v: Perfect played %v (%v), the best are %v (%v)", rules, s.Moves(), m, a.Moves[m], a.Best, a.Outcome)
+				}
+			}
+			for _, m := range s.LegalMoves() {
+				_ = s.Apply(m)
+				walk(s)
+				s.Undo()
+			}
+		}
+		walk(game.NewState(rules))
+	}
+}
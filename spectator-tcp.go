@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/protocol"
+)
+
+type watchingMsg struct {
+	conn  *protocol.Conn
+	rules game.Rules
+}
+
+// SpectatorModel follows a TCP game without taking part. The board is laid
+// out by TCPmodel, which plays no side here and so takes no input.
+type SpectatorModel struct {
+	game TCPmodel
+}
+
+func NewSpectatorModel(width, height int, conn *protocol.Conn, rules game.Rules) SpectatorModel {
+	return SpectatorModel{game: newTCPModel(width, height, conn, session{}, game.Empty, rules)}
+}
+
+func (m SpectatorModel) Init() tea.Cmd {
+	return receive(m.game.conn)
+}
+
+func (m SpectatorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case constants.M:
+			m.game.conn.Close()
+			return initialModel(m.game.width, m.game.height), nil
+		case constants.Quit, constants.CtrlC, constants.Esc:
+			m.game.conn.Close()
+			return m, tea.Quit
+		}
+
+	case peerMessage:
+		if msg.conn != m.game.conn {
+			return m, nil
+		}
+		m.game = m.follow(msg.msg)
+		return m, receive(m.game.conn)
+
+	case errMsg:
+		if msg.conn != m.game.conn {
+			return m, nil
+		}
+		m.game.conn.Close()
+		m.game.infoMessage = "The host has left, the game is over."
+
+	case tea.WindowSizeMsg:
+		m.game.width = msg.Width
+		m.game.height = msg.Height
+	}
+	return m, nil
+}
+
+// follow brings the board up to date with what the host sent.
+func (m SpectatorModel) follow(msg protocol.Message) TCPmodel {
+	g := m.game
+	switch msg.Type {
+	case protocol.TypeSnapshot:
+		state, err := msg.Replay(g.state.Rules())
+		if err != nil {
+			g.infoMessage = formatErrorMessage("Bad snapshot: " + err.Error())
+			return g
+		}
+		g.state = state
+		g.infoMessage = "A new game is on."
+		if state.Plies() > 0 {
+			g.infoMessage = fmt.Sprintf("Watching from move %d.", state.Plies())
+		}
+	case protocol.TypeMove:
+		player, move := msg.Move()
+		if msg.Ply != g.state.Plies()+1 || g.state.Play(player, move) != nil {
+			g.infoMessage = "Lost track of the game, waiting for the next one."
+			return g
+		}
+		g.infoMessage = fmt.Sprintf("%s marked cell [%d, %d]", player, move.Row, move.Col)
+		if winner := g.state.Winner(); winner != game.Empty {
+			g.infoMessage = winMessage(g.state.Rules(), winner)
+		} else if g.state.IsDraw() {
+			g.infoMessage = "It's a draw!"
+		}
+	case protocol.TypeResign:
+		player, _ := game.ParsePlayer(msg.Player)
+		g.infoMessage = fmt.Sprintf("%s resigned.", player)
+	}
+	return g
+}
+
+func (m SpectatorModel) View() string {
+	return m.game.View()
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/profile"
+	"github.com/spf13/cobra"
+)
+
+// newStatsCmd prints the leaderboard.
+func newStatsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats",
+		Short: "Show the ratings of the profiles on this machine",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			book, err := profile.Load()
+			if err != nil {
+				return err
+			}
+			return printLeaderboard(os.Stdout, book.Leaderboard())
+		},
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/ai"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/notation"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/tournament"
+	"github.com/spf13/cobra"
+)
+
+// newTournamentCmd plays built-in levels and engines against each other.
+func newTournamentCmd() *cobra.Command {
+	var formatName, out string
+	var games, rounds, workers int
+	var seed int64
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		Use:   "tournament <player> <player>...",
+		Short: "Play computer players against each other",
+		Long: "Play a tournament between built-in levels (easy, medium, perfect, mcts, menace) and engine commands such as './bot --fast', " +
+			"which must speak the protocol of the engine subcommand. Prints the standings and a crosstable and saves every game.",
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := tournament.ParseFormat(formatName)
+			if err != nil {
+				return err
+			}
+			ai.Seeded = cmd.Flags().Changed("seed")
+			if !ai.Seeded {
+				seed = time.Now().UnixNano()
+			}
+			cfg := tournament.Config{
+				Format:  format,
+				Rules:   menuRules(),
+				Games:   games,
+				Rounds:  rounds,
+				Workers: workers,
+				Seed:    seed,
+				Done: func(g tournament.Game) {
+					rec := g.Record
+					fmt.Fprintf(os.Stderr, "round %d: %s - %s %s\n", g.Round, rec.X, rec.O, rec.Result)
+				},
+			}
+
+			result, err := tournament.Run(tournamentEntrants(args, timeout), cfg)
+			if err != nil {
+				return err
+			}
+
+			if out == "" {
+				out = "tournament-" + time.Now().Format("20060102-150405") + notation.Extension
+			}
+			if err := saveTournament(out, result); err != nil {
+				return err
+			}
+			fmt.Println()
+			if err := tournament.WriteStandings(os.Stdout, result); err != nil {
+				return err
+			}
+			fmt.Println()
+			if err := tournament.WriteCrosstable(os.Stdout, result); err != nil {
+				return err
+			}
+			fmt.Printf("\n%d games saved to %s\n", len(result.Games), out)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&formatName, "format", tournament.RoundRobin.String(), "round-robin or swiss")
+	cmd.Flags().IntVar(&games, "games", 2, "games per pairing, colors alternate")
+	cmd.Flags().IntVar(&rounds, "rounds", 3, "rounds of a Swiss tournament")
+	cmd.Flags().IntVar(&workers, "workers", 0, "games played at once, one per CPU by default")
+	cmd.Flags().DurationVar(&timeout, "engine-timeout", 30*time.Second, "time an engine has to answer before it loses, 0 for no limit")
+	cmd.Flags().Int64Var(&seed, "seed", 0, "seed for the built-in players, random by default")
+	cmd.Flags().StringVarP(&out, "out", "o", "", "file for the games, tournament-<time>.ttt by default")
+	return cmd
+}
+
+// tournamentEntrants reads built-in levels by name and anything else as an
+// engine command, named after its program.
+func tournamentEntrants(args []string, timeout time.Duration) []tournament.Entrant {
+	entrants := make([]tournament.Entrant, len(args))
+	for i, arg := range args {
+		if d, err := ai.ParseDifficulty(arg); err == nil {
+			entrants[i] = tournament.Builtin(d)
+		} else if fields := strings.Fields(arg); len(fields) > 0 {
+			entrants[i] = tournament.Engine(filepath.Base(fields[0]), arg, timeout)
+		} else {
+			entrants[i] = tournament.Engine(arg, arg, timeout)
+		}
+	}
+	return entrants
+}
+
+// saveTournament writes every game of a tournament to one file.
+func saveTournament(path string, result tournament.Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	for i, g := range result.Games {
+		if i > 0 {
+			fmt.Fprintln(f)
+		}
+		if err := notation.Write(f, g.Record); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
@@ -0,0 +1,133 @@
+package tournament
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// Standing is how an entrant did.
+type Standing struct {
+	Entrant int
+	Name    string
+	// Points score one for a win or a bye and a half for a draw
+	Points float64
+	Wins   int
+	Draws  int
+	Losses int
+	Byes   int
+}
+
+// Games is the number of games played, byes excluded.
+func (s Standing) Games() int {
+	return s.Wins + s.Draws + s.Losses
+}
+
+// Standings ranks the entrants by points, then by wins. Ties keep the order
+// of entry.
+func (r Result) Standings() []Standing {
+	standings := make([]Standing, len(r.Names))
+	for i, name := range r.Names {
+		standings[i] = Standing{Entrant: i, Name: name}
+		if i < len(r.Byes) {
+			standings[i].Byes = r.Byes[i]
+			standings[i].Points = float64(r.Byes[i])
+		}
+	}
+	for _, g := range r.Games {
+		x, o := &standings[g.X], &standings[g.O]
+		switch g.Record.Result.Winner() {
+		case game.X:
+			x.Wins++
+			x.Points++
+			o.Losses++
+		case game.O:
+			o.Wins++
+			o.Points++
+			x.Losses++
+		default:
+			x.Draws++
+			o.Draws++
+			x.Points += 0.5
+			o.Points += 0.5
+		}
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		if standings[i].Points != standings[j].Points {
+			return standings[i].Points > standings[j].Points
+		}
+		return standings[i].Wins > standings[j].Wins
+	})
+	return standings
+}
+
+// Crosstable returns the points entrant i scored against entrant j, and
+// whether they met at all.
+func (r Result) Crosstable() (points [][]float64, met [][]bool) {
+	points = make([][]float64, len(r.Names))
+	met = make([][]bool, len(r.Names))
+	for i := range points {
+		points[i] = make([]float64, len(r.Names))
+		met[i] = make([]bool, len(r.Names))
+	}
+	for _, g := range r.Games {
+		met[g.X][g.O], met[g.O][g.X] = true, true
+		switch g.Record.Result.Winner() {
+		case game.X:
+			points[g.X][g.O]++
+		case game.O:
+			points[g.O][g.X]++
+		default:
+			points[g.X][g.O] += 0.5
+			points[g.O][g.X] += 0.5
+		}
+	}
+	return points, met
+}
+
+// WriteStandings prints the standings as a table.
+func WriteStandings(w io.Writer, r Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "#\tNAME\tPOINTS\tGAMES\tW\tD\tL\t")
+	for i, s := range r.Standings() {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\t%d\t%d\t\n", i+1, s.Name, formatPoints(s.Points), s.Games(), s.Wins, s.Draws, s.Losses)
+	}
+	return tw.Flush()
+}
+
+// WriteCrosstable prints who scored what against whom, in the order of the
+// standings. Columns are numbered by rank, "." marks pairings that never met.
+func WriteCrosstable(w io.Writer, r Result) error {
+	standings := r.Standings()
+	points, met := r.Crosstable()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "#\tNAME\t")
+	for i := range standings {
+		fmt.Fprintf(tw, "%d\t", i+1)
+	}
+	fmt.Fprintln(tw, "TOTAL\t")
+	for i, row := range standings {
+		fmt.Fprintf(tw, "%d\t%s\t", i+1, row.Name)
+		for _, col := range standings {
+			switch {
+			case row.Entrant == col.Entrant:
+				fmt.Fprint(tw, "x\t")
+			case !met[row.Entrant][col.Entrant]:
+				fmt.Fprint(tw, ".\t")
+			default:
+				fmt.Fprintf(tw, "%s\t", formatPoints(points[row.Entrant][col.Entrant]))
+			}
+		}
+		fmt.Fprintf(tw, "%s\t\n", formatPoints(row.Points))
+	}
+	return tw.Flush()
+}
+
+func formatPoints(p float64) string {
+	return strconv.FormatFloat(p, 'f', -1, 64)
+}
@@ -0,0 +1,408 @@
+// Package tournament plays computer players against each other, built-in
+// levels and external engines alike, and tallies the results.
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/ai"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/engine"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/notation"
+)
+
+// Mode is the mode tag of tournament games in the notation.
+const Mode = "tournament"
+
+// Format decides who meets whom.
+type Format int
+
+const (
+	// RoundRobin pairs every entrant with every other one.
+	RoundRobin Format = iota
+	// Swiss plays a fixed number of rounds, each pairing entrants with the
+	// same score who have not met yet.
+	Swiss
+)
+
+// Formats lists every format.
+var Formats = []Format{RoundRobin, Swiss}
+
+func (f Format) String() string {
+	switch f {
+	case RoundRobin:
+		return "round-robin"
+	case Swiss:
+		return "swiss"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// ParseFormat accepts a format by name, ignoring case.
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(name, f.String()) {
+			return f, nil
+		}
+	}
+	return RoundRobin, fmt.Errorf("unknown format %q: want round-robin or swiss", name)
+}
+
+// Entrant is a player taking part in a tournament.
+type Entrant interface {
+	Name() string
+	// Seat prepares the entrant for one game. Games run concurrently, so
+	// every game gets a seat of its own.
+	Seat(rules game.Rules, seed int64) (Seat, error)
+}
+
+// Seat plays one game for an entrant.
+type Seat interface {
+	// Move picks the move for the side to move. It must not modify s.
+	Move(s *game.State) (game.Move, error)
+	Close() error
+}
+
+// Builtin enters the built-in opponent of difficulty d.
+func Builtin(d ai.Difficulty) Entrant {
+	return builtin{d}
+}
+
+type builtin struct {
+	level ai.Difficulty
+}
+
+func (b builtin) Name() string {
+	return b.level.String()
+}
+
+func (b builtin) Seat(rules game.Rules, seed int64) (Seat, error) {
+	return agentSeat{ai.New(b.level, seed)}, nil
+}
+
+type agentSeat struct {
+	ai.Agent
+}
+
+func (agentSeat) Close() error {
+	return nil
+}
+
+// Engine enters an external program speaking the engine protocol, started
+// from command once for every game. An engine that does not answer within
+// timeout loses the game, zero waits forever.
+func Engine(name, command string, timeout time.Duration) Entrant {
+	return external{name: name, command: command, timeout: timeout}
+}
+
+type external struct {
+	name    string
+	command string
+	timeout time.Duration
+}
+
+func (e external) Name() string {
+	return e.name
+}
+
+func (e external) Seat(rules game.Rules, seed int64) (Seat, error) {
+	c, err := engine.Start(e.command)
+	if err != nil {
+		return nil, err
+	}
+	c.Timeout = e.timeout
+	newgame := fmt.Sprintf("newgame size=%d k=%d", rules.Size, rules.K)
+	if rules.Misere {
+		newgame += " misere=true"
+	}
+	if _, err := c.Command(newgame); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	return &engineSeat{client: c}, nil
+}
+
+// engineSeat tells the engine the moves it has not seen before asking
+// for its own.
+type engineSeat struct {
+	client *engine.Client
+	known  int
+}
+
+func (e *engineSeat) Move(s *game.State) (game.Move, error) {
+	moves := s.Moves()
+	for _, m := range moves[e.known:] {
+		if _, err := e.client.Command("play " + m.String()); err != nil {
+			return game.Move{}, err
+		}
+	}
+	answer, err := e.client.Command("genmove")
+	if err != nil {
+		return game.Move{}, err
+	}
+	m, err := game.ParseMove(answer)
+	if err != nil {
+		return game.Move{}, fmt.Errorf("genmove: %w", err)
+	}
+	// The engine has played its move already
+	e.known = len(moves) + 1
+	return m, nil
+}
+
+func (e *engineSeat) Close() error {
+	return e.client.Close()
+}
+
+// Config sets up a tournament.
+type Config struct {
+	Format Format
+	Rules  game.Rules
+	// Games is how many games each pairing plays, alternating colors
+	Games int
+	// Rounds is the number of Swiss rounds, ignored by round robins
+	Rounds int
+	// Workers is how many games are played at once, one per CPU when zero
+	Workers int
+	// Seed makes the built-in players' random choices reproducible
+	Seed int64
+	// Done, when set, is called after every game, one call at a time
+	Done func(Game)
+}
+
+// Game is one game of a tournament.
+type Game struct {
+	Round int
+	// X and O index the entrants
+	X      int
+	O      int
+	Record notation.Record
+}
+
+// Result holds every game of a finished tournament.
+type Result struct {
+	Names []string
+	Games []Game
+	// Byes counts the rounds each entrant sat out, scored as wins
+	Byes []int
+}
+
+// Run plays a tournament between the entrants.
+func Run(entrants []Entrant, cfg Config) (Result, error) {
+	if len(entrants) < 2 {
+		return Result{}, errors.New("a tournament needs at least two entrants")
+	}
+	if err := cfg.Rules.Validate(); err != nil {
+		return Result{}, err
+	}
+	if cfg.Rules.Variant != game.Classic {
+		return Result{}, errors.New("tournaments only play the classic variant")
+	}
+	if cfg.Games < 1 {
+		return Result{}, errors.New("every pairing must play at least one game")
+	}
+	for _, e := range entrants {
+		if b, ok := e.(builtin); ok {
+			if err := b.level.CheckRules(cfg.Rules); err != nil {
+				return Result{}, err
+			}
+		}
+	}
+	if cfg.Workers < 1 {
+		cfg.Workers = runtime.NumCPU()
+	}
+
+	t := &tourney{entrants: entrants, cfg: cfg}
+	// Number repeated names so the records tell the entrants apart
+	t.result.Names = make([]string, len(entrants))
+	seen := map[string]int{}
+	for i, e := range entrants {
+		name := e.Name()
+		if seen[name]++; seen[name] > 1 {
+			name = fmt.Sprintf("%s #%d", name, seen[name])
+		}
+		t.result.Names[i] = name
+	}
+	t.result.Byes = make([]int, len(entrants))
+
+	switch cfg.Format {
+	case RoundRobin:
+		var pairs [][2]int
+		for i := range entrants {
+			for j := i + 1; j < len(entrants); j++ {
+				// Alternate who starts so nobody always opens
+				if len(pairs)%2 == 0 {
+					pairs = append(pairs, [2]int{i, j})
+				} else {
+					pairs = append(pairs, [2]int{j, i})
+				}
+			}
+		}
+		t.play(1, pairs)
+	case Swiss:
+		if cfg.Rounds < 1 {
+			return Result{}, errors.New("a Swiss tournament needs at least one round")
+		}
+		for round := 1; round <= cfg.Rounds; round++ {
+			t.play(round, t.swissPairs())
+		}
+	default:
+		return Result{}, fmt.Errorf("unknown format %v", cfg.Format)
+	}
+	return t.result, nil
+}
+
+type tourney struct {
+	entrants []Entrant
+	cfg      Config
+	result   Result
+}
+
+// play runs the games of one round on the worker pool. The first entrant of
+// a pair takes X in the first game.
+func (t *tourney) play(round int, pairs [][2]int) {
+	var games []Game
+	for _, pair := range pairs {
+		for k := 0; k < t.cfg.Games; k++ {
+			x, o := pair[0], pair[1]
+			if k%2 == 1 {
+				x, o = o, x
+			}
+			games = append(games, Game{Round: round, X: x, O: o})
+		}
+	}
+
+	jobs := make(chan int)
+	done := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(t.cfg.Workers, len(games)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				g := &games[i]
+				seed := t.cfg.Seed + int64(len(t.result.Games)+i)*2
+				g.Record = t.playGame(g.X, g.O, seed)
+				done <- i
+			}
+		}()
+	}
+	go func() {
+		for i := range games {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(done)
+	}()
+	for i := range done {
+		if t.cfg.Done != nil {
+			t.cfg.Done(games[i])
+		}
+	}
+	t.result.Games = append(t.result.Games, games...)
+}
+
+// swissPairs pairs the entrants best score first, each with the next one it
+// has not met yet where possible. With an odd number of entrants the lowest
+// one without a bye sits out and scores a win.
+func (t *tourney) swissPairs() [][2]int {
+	standings := t.result.Standings()
+	order := make([]int, len(standings))
+	for i, s := range standings {
+		order[i] = s.Entrant
+	}
+
+	if len(order)%2 == 1 {
+		bye := len(order) - 1
+		for i := len(order) - 1; i >= 0; i-- {
+			if t.result.Byes[order[i]] < t.result.Byes[order[bye]] {
+				bye = i
+			}
+		}
+		t.result.Byes[order[bye]]++
+		order = append(order[:bye], order[bye+1:]...)
+	}
+
+	met := make(map[[2]int]bool)
+	whites := make([]int, len(t.entrants))
+	for _, g := range t.result.Games {
+		met[[2]int{g.X, g.O}], met[[2]int{g.O, g.X}] = true, true
+		whites[g.X]++
+	}
+
+	var pairs [][2]int
+	for len(order) > 0 {
+		a, partner := order[0], 1
+		for j := 1; j < len(order); j++ {
+			if !met[[2]int{a, order[j]}] {
+				partner = j
+				break
+			}
+		}
+		b := order[partner]
+		order = append(order[1:partner], order[partner+1:]...)
+
+		// Give X to whoever had it less
+		if whites[b] < whites[a] {
+			a, b = b, a
+		}
+		pairs = append(pairs, [2]int{a, b})
+	}
+	return pairs
+}
+
+// playGame plays one game between the entrants x and o to the end. An
+// entrant that fails to move or plays an illegal move loses.
+func (t *tourney) playGame(x, o int, seed int64) notation.Record {
+	rules := t.cfg.Rules
+	s := game.NewState(rules)
+	started := time.Now()
+	rec := func(loser game.Player, termination string) notation.Record {
+		r := notation.New(s, t.result.Names[x], t.result.Names[o], started)
+		r.Ended = time.Now()
+		r.Mode = Mode
+		if loser != game.Empty {
+			r.Result = notation.Decided(loser.Opponent())
+			r.Termination = termination
+		}
+		return r
+	}
+
+	seats := map[game.Player]Seat{}
+	for i, side := range []game.Player{game.X, game.O} {
+		entrant := t.entrants[x]
+		if side == game.O {
+			entrant = t.entrants[o]
+		}
+		seat, err := entrant.Seat(rules, seed+int64(i))
+		if err != nil {
+			for _, s := range seats {
+				_ = s.Close()
+			}
+			return rec(side, "engine error: "+err.Error())
+		}
+		seats[side] = seat
+	}
+	defer func() {
+		for _, s := range seats {
+			_ = s.Close()
+		}
+	}()
+
+	for !s.IsTerminal() {
+		side := s.Turn()
+		m, err := seats[side].Move(s.Clone())
+		if err != nil {
+			return rec(side, "engine error: "+err.Error())
+		}
+		if err := s.Apply(m); err != nil {
+			return rec(side, "illegal move "+m.String())
+		}
+	}
+	return rec(game.Empty, "")
+}
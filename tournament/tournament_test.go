@@ -0,0 +1,236 @@
+package tournament
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/ai"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/notation"
+)
+
+// scripted is an entrant that plays the first legal cell, or fails the way
+// its name says.
+type scripted string
+
+func (e scripted) Name() string {
+	return string(e)
+}
+
+func (e scripted) Seat(rules game.Rules, seed int64) (Seat, error) {
+	if e == "crashes" {
+		return nil, errors.New("no such program")
+	}
+	return e, nil
+}
+
+func (e scripted) Move(s *game.State) (game.Move, error) {
+	switch e {
+	case "hangs":
+		return game.Move{}, errors.New("engine did not answer")
+	case "cheats":
+		// The centre, whether taken or not
+		return game.Move{Row: 1, Col: 1}, nil
+	}
+	return s.LegalMoves()[0], nil
+}
+
+func (scripted) Close() error {
+	return nil
+}
+
+func TestRoundRobin(t *testing.T) {
+	entrants := []Entrant{scripted("first"), scripted("first"), scripted("hangs")}
+	var done int
+	result, err := Run(entrants, Config{Format: RoundRobin, Rules: game.Standard(), Games: 2, Workers: 2, Done: func(Game) { done++ }})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := []string{"first", "first #2", "hangs"}; !reflect.DeepEqual(result.Names, want) {
+		t.Errorf("Names = %v, want %v", result.Names, want)
+	}
+	if len(result.Games) != 6 || done != 6 {
+		t.Fatalf("played %d games, Done called %d times, want 6", len(result.Games), done)
+	}
+
+	sides := map[[2]int]int{}
+	for _, g := range result.Games {
+		sides[[2]int{g.X, g.O}]++
+		if g.Record.Mode != Mode || g.Record.X != result.Names[g.X] || g.Record.O != result.Names[g.O] {
+			t.Errorf("game %d-%d recorded as %+v", g.X, g.O, g.Record)
+		}
+		// First legal cell against itself ends on the anti-diagonal
+		if g.X != 2 && g.O != 2 && g.Record.Result != notation.XWins {
+			t.Errorf("game %d-%d ended %s, want 1-0", g.X, g.O, g.Record.Result)
+		}
+	}
+	for a := 0; a < 3; a++ {
+		for b := a + 1; b < 3; b++ {
+			if sides[[2]int{a, b}] != 1 || sides[[2]int{b, a}] != 1 {
+				t.Errorf("%d and %d did not play one game with each color", a, b)
+			}
+		}
+	}
+
+	standings := result.Standings()
+	if last := standings[2]; last.Name != "hangs" || last.Losses != 4 || last.Points != 0 {
+		t.Errorf("last = %+v, want hangs with 4 losses", last)
+	}
+	for _, g := range result.Games {
+		if g.X == 2 && !strings.HasPrefix(g.Record.Termination, "engine error") {
+			t.Errorf("forfeit recorded with termination %q", g.Record.Termination)
+		}
+	}
+}
+
+func TestForfeits(t *testing.T) {
+	tests := []struct {
+		loser       Entrant
+		termination string
+	}{
+		{scripted("crashes"), "engine error: no such program"},
+		{scripted("hangs"), "engine error: engine did not answer"},
+		{scripted("cheats"), "illegal move b2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.loser.Name(), func(t *testing.T) {
+			// The loser plays O, so the cheat finds the centre taken
+			result, err := Run([]Entrant{scripted("first"), tt.loser}, Config{Rules: game.Rules{Size: 3, K: 3}, Games: 1})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			rec := result.Games[0].Record
+			if rec.Result != notation.XWins || rec.Termination != tt.termination {
+				t.Errorf("game ended %s by %q, want 1-0 by %q", rec.Result, rec.Termination, tt.termination)
+			}
+		})
+	}
+}
+
+func TestRunRejects(t *testing.T) {
+	two := []Entrant{scripted("a"), scripted("b")}
+	tests := []struct {
+		name     string
+		entrants []Entrant
+		cfg      Config
+	}{
+		{"one entrant", two[:1], Config{Rules: game.Standard(), Games: 1}},
+		{"no games", two, Config{Rules: game.Standard()}},
+		{"bad rules", two, Config{Rules: game.Rules{Size: 3, K: 4}, Games: 1}},
+		{"ultimate", two, Config{Rules: game.UltimateRules(), Games: 1}},
+		{"swiss without rounds", two, Config{Format: Swiss, Rules: game.Standard(), Games: 1}},
+		{"level unfit for the board", []Entrant{scripted("a"), Builtin(ai.Learning)}, Config{Rules: game.Rules{Size: 4, K: 3}, Games: 1}},
+	}
+	for _, tt := range tests {
+		if _, err := Run(tt.entrants, tt.cfg); err == nil {
+			t.Errorf("%s: Run() returned no error", tt.name)
+		}
+	}
+}
+
+// played builds a finished game between entrants x and o.
+func played(round, x, o int, result notation.Result) Game {
+	return Game{Round: round, X: x, O: o, Record: notation.Record{Result: result}}
+}
+
+func TestSwissPairs(t *testing.T) {
+	// After one round 0 beat 1, 2 beat 3 and 4 sat out
+	tr := &tourney{
+		entrants: make([]Entrant, 5),
+		result: Result{
+			Names: []string{"a", "b", "c", "d", "e"},
+			Games: []Game{played(1, 0, 1, notation.XWins), played(1, 2, 3, notation.XWins)},
+			Byes:  []int{0, 0, 0, 0, 1},
+		},
+	}
+	pairs := tr.swissPairs()
+
+	// The leaders meet with X to whoever had it less, the lowest entrant
+	// without a bye sits out and the rest pair up without a rematch
+	want := [][2]int{{0, 2}, {4, 1}}
+	if !samePairs(pairs, want) {
+		t.Errorf("swissPairs() = %v, want %v", pairs, want)
+	}
+	if want := []int{0, 0, 0, 1, 1}; !reflect.DeepEqual(tr.result.Byes, want) {
+		t.Errorf("Byes = %v, want %v", tr.result.Byes, want)
+	}
+}
+
+// samePairs compares pairings ignoring who takes X where both had it as
+// often.
+func samePairs(got, want [][2]int) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] && got[i] != [2]int{want[i][1], want[i][0]} {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSwiss(t *testing.T) {
+	entrants := []Entrant{scripted("first"), scripted("first"), scripted("first")}
+	result, err := Run(entrants, Config{Format: Swiss, Rules: game.Standard(), Games: 1, Rounds: 3})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	// Every round one of the three sits out, each exactly once
+	if want := []int{1, 1, 1}; !reflect.DeepEqual(result.Byes, want) {
+		t.Errorf("Byes = %v, want %v", result.Byes, want)
+	}
+	if len(result.Games) != 3 {
+		t.Fatalf("played %d games, want 3", len(result.Games))
+	}
+	met := map[[2]int]bool{}
+	for _, g := range result.Games {
+		pair := [2]int{min(g.X, g.O), max(g.X, g.O)}
+		if met[pair] {
+			t.Errorf("%v met twice", pair)
+		}
+		met[pair] = true
+	}
+}
+
+func TestStandings(t *testing.T) {
+	r := Result{
+		Names: []string{"a", "b", "c", "d"},
+		Games: []Game{
+			played(1, 0, 1, notation.Draw),
+			played(1, 2, 3, notation.OWins),
+			played(2, 3, 0, notation.Draw),
+			played(2, 1, 2, notation.XWins),
+		},
+		Byes: []int{0, 0, 1, 0},
+	}
+	got := r.Standings()
+	// b and d both won once and drew once, a drew twice and c scored only
+	// its bye; ties keep the order of entry
+	want := []Standing{
+		{Entrant: 1, Name: "b", Points: 1.5, Wins: 1, Draws: 1},
+		{Entrant: 3, Name: "d", Points: 1.5, Wins: 1, Draws: 1},
+		{Entrant: 0, Name: "a", Points: 1, Draws: 2},
+		{Entrant: 2, Name: "c", Points: 1, Losses: 2, Byes: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Standings() = %+v, want %+v", got, want)
+	}
+
+	points, met := r.Crosstable()
+	if points[3][2] != 1 || points[2][3] != 0 || points[0][1] != 0.5 || !met[0][3] || met[0][2] {
+		t.Errorf("Crosstable() = %v, %v", points, met)
+	}
+
+	var b strings.Builder
+	if err := WriteCrosstable(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	// a never met c, shown as a dot in a's row
+	lines := strings.Split(b.String(), "\n")
+	if len(lines) < 4 || !strings.Contains(lines[3], ".") || !strings.Contains(lines[3], "x") {
+		t.Errorf("WriteCrosstable() =\n%s", b.String())
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/ai"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/spf13/cobra"
+)
+
+// newTrainCmd lets the learning computer practise and prints its curve.
+func newTrainCmd() *cobra.Command {
+	var games, blocks int
+	var against string
+	var seed int64
+	cmd := &cobra.Command{
+		Use:   "train",
+		Short: "Let the learning computer practise",
+		Long: "Play the MENACE computer against another level, or against itself, and print its learning curve. " +
+			"What it learns is kept for later games, one file per set of rules. It only plays on 3x3 boards.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if games < 1 || blocks < 1 {
+				return errors.New("--games and --blocks must be at least 1")
+			}
+			if selectedVariant == game.Ultimate {
+				return errors.New("the computer only plays the classic variant")
+			}
+			if err := ai.CheckMenace(selectedRules); err != nil {
+				return err
+			}
+			ai.Seeded = cmd.Flags().Changed("seed")
+			if !ai.Seeded {
+				seed = time.Now().UnixNano()
+			}
+			menace := ai.NewMenace(ai.MenaceDir, seed)
+			var opponent ai.Agent
+			if against != "self" {
+				d, err := ai.ParseDifficulty(against)
+				if err != nil {
+					return err
+				}
+				if d != ai.Learning {
+					opponent = ai.New(d, seed+1)
+				}
+			}
+
+			if err := trainMenace(os.Stdout, menace, opponent, selectedRules, games, blocks); err != nil {
+				return err
+			}
+			boxes, err := menace.Boxes(selectedRules)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("\nMENACE has learnt from %d games and knows %d positions", boxes.Games, len(boxes.Beads))
+			if menace.Dir != "" {
+				fmt.Printf(", kept in %s", menace.Path(selectedRules))
+			}
+			fmt.Println()
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&games, "games", 1000, "games to play")
+	cmd.Flags().IntVar(&blocks, "blocks", 20, "lines of the learning curve")
+	cmd.Flags().StringVar(&against, "against", "self", "opponent: self, easy, medium, perfect or mcts")
+	cmd.Flags().Int64Var(&seed, "seed", 0, "seed for the random choices, random by default")
+	return cmd
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/ai"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+)
+
+// curveWidth is the length of the bars of the learning curve.
+const curveWidth = 40
+
+// trainMenace lets the learning computer play games against opponent, or
+// against itself when opponent is nil, switching sides every game. It
+// prints the learning curve in blocks of games and saves what it learnt.
+func trainMenace(w io.Writer, menace *ai.Menace, opponent ai.Agent, rules game.Rules, games, blocks int) error {
+	blockSize := max(1, (games+blocks-1)/blocks)
+	fmt.Fprintf(w, "%-13s %5s %5s %5s\n", "GAMES", "WIN", "DRAW", "LOSS")
+
+	var wins, draws, losses int
+	for i := 0; i < games; i++ {
+		side := game.X
+		if i%2 == 1 {
+			side = game.O
+		}
+
+		s := game.NewState(rules)
+		for !s.IsTerminal() {
+			var agent ai.Agent = menace
+			if opponent != nil && s.Turn() != side {
+				agent = opponent
+			}
+			m, err := agent.Move(s)
+			if err != nil {
+				return err
+			}
+			if err := s.Apply(m); err != nil {
+				return err
+			}
+		}
+		if err := menace.Learn(rules, s.Moves(), s.Winner()); err != nil {
+			return err
+		}
+
+		switch s.Winner() {
+		case side:
+			wins++
+		case game.Empty:
+			draws++
+		default:
+			losses++
+		}
+		if played := i%blockSize + 1; played == blockSize || i == games-1 {
+			printCurve(w, i+2-played, i+1, wins, draws, losses)
+			wins, draws, losses = 0, 0, 0
+		}
+	}
+	return menace.Save()
+}
+
+// printCurve writes the results of one block as percentages and a bar of
+// wins, draws and losses.
+func printCurve(w io.Writer, first, last, wins, draws, losses int) {
+	total := wins + draws + losses
+	percent := func(n int) int { return (100*n + total/2) / total }
+	winBar := (curveWidth*wins + total/2) / total
+	drawBar := min(curveWidth-winBar, (curveWidth*draws+total/2)/total)
+	bar := strings.Repeat("█", winBar) + strings.Repeat("▒", drawBar) + strings.Repeat("░", curveWidth-winBar-drawBar)
+	fmt.Fprintf(w, "%-13s %4d%% %4d%% %4d%%  %s\n", fmt.Sprintf("%d-%d", first, last), percent(wins), percent(draws), percent(losses), bar)
+}
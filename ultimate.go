@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/archive"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/clock"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/notation"
+)
+
+// UltimateModel is a hot-seat game of Ultimate Tic-Tac-Toe.
+type UltimateModel struct {
+	width        int
+	height       int
+	selectedRow  int
+	selectedCol  int
+	state        *game.UltimateState
+	errorMessage string
+	infoMessage  string
+	series       *series
+	clock        *clock.Clock
+	history      history
+	reviewing    bool
+	started      time.Time
+}
+
+func NewUltimateModel(width, height int) *UltimateModel {
+	return &UltimateModel{
+		width:       width,
+		height:      height,
+		selectedRow: 4,
+		selectedCol: 4,
+		state:       game.NewUltimateState(),
+		series:      newSeries("Player 1", "Player 2"),
+		started:     time.Now(),
+	}
+}
+
+// withProfiles names the seats after both profiles and so rates the games.
+func (m *UltimateModel) withProfiles(x, o string) *UltimateModel {
+	m.series.names = [2]string{x, o}
+	m.series.rated = true
+	return m
+}
+
+// withClock times the game under c.
+func (m *UltimateModel) withClock(c clock.Control) *UltimateModel {
+	m.clock = clock.New(c)
+	return m
+}
+
+func (m *UltimateModel) Init() tea.Cmd {
+	if m.clock != nil {
+		m.clock.Start(m.state.Turn(), time.Now())
+	}
+	return tickClock(m.clock)
+}
+
+func (m *UltimateModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.reviewing {
+			return m.review(msg)
+		}
+		switch msg.String() {
+		case constants.Up:
+			m.moveCursor(-1, 0)
+		case constants.Down:
+			m.moveCursor(1, 0)
+		case constants.Left:
+			m.moveCursor(0, -1)
+		case constants.Right:
+			m.moveCursor(0, 1)
+		case constants.Enter:
+			err := m.state.Apply(game.Move{Row: m.selectedRow, Col: m.selectedCol})
+			switch {
+			case errors.Is(err, game.ErrOccupied):
+				m.errorMessage = "Cannot overwrite existing marker!"
+			case errors.Is(err, game.ErrWrongBoard):
+				m.errorMessage = "Play in a highlighted board!"
+			case err != nil:
+				m.errorMessage = err.Error()
+			default:
+				m.errorMessage = ""
+				return m.afterMove()
+			}
+		case constants.Undo, constants.CtrlZ:
+			if m.errorMessage = m.historyLocked(); m.errorMessage == "" && !m.history.undo(m.state) {
+				m.errorMessage = "Nothing to undo!"
+			}
+		case constants.Redo, constants.CtrlY:
+			if m.errorMessage = m.historyLocked(); m.errorMessage == "" && !m.history.redo(m.state) {
+				m.errorMessage = "Nothing to redo!"
+			}
+		case constants.Tab:
+			if m.errorMessage = m.historyLocked(); m.errorMessage == "" {
+				m.reviewing = true
+			}
+		case constants.Save:
+			m.infoMessage = savedMessage(saveGame(m.record()))
+		case constants.CtrlC, constants.Esc:
+			return m, tea.Quit
+		}
+
+	case clockTickMsg:
+		if msg.clock != m.clock {
+			return m, nil
+		}
+		if flagged := m.clock.Check(msg.at); flagged != game.Empty {
+			return m.finish(flagged.Opponent(), constants.LoseMsgStyle.Render(flagMessage(flagged)))
+		}
+		return m, tickClock(m.clock)
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	}
+	return m, nil
+}
+
+// afterMove ends the game once the last move decided it.
+func (m *UltimateModel) afterMove() (tea.Model, tea.Cmd) {
+	if move, ok := m.state.LastMove(); ok {
+		m.history.record(move)
+	}
+	if m.clock != nil {
+		m.clock.Press(time.Now())
+	}
+	if winner := m.state.Winner(); winner != game.Empty {
+		endMessage := fmt.Sprintf("Player %s wins!", winner)
+		return m.finish(winner, constants.WinMsgStyle.Render(endMessage))
+	}
+	if m.state.IsDraw() {
+		return m.finish(game.Empty, constants.DrawMsgStyle.Render("It's a draw!"))
+	}
+	return m, nil
+}
+
+// historyLocked explains why the history cannot be used right now.
+func (m *UltimateModel) historyLocked() string {
+	if m.clock != nil {
+		return "Undo is off in timed games!"
+	}
+	return ""
+}
+
+// review steps through the history, the game goes on from the position
+// shown when leaving.
+func (m *UltimateModel) review(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case constants.Up, constants.Left:
+		m.history.jump(m.state, m.history.ply-1)
+	case constants.Down, constants.Right:
+		m.history.jump(m.state, m.history.ply+1)
+	case constants.Home:
+		m.history.jump(m.state, 0)
+	case constants.End:
+		m.history.jump(m.state, len(m.history.moves))
+	case constants.Tab, constants.Enter:
+		m.reviewing = false
+	case constants.CtrlC, constants.Esc:
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// finish scores the game and shows the outcome with the offer of a rematch.
+func (m *UltimateModel) finish(winner game.Player, endMessage string) (tea.Model, tea.Cmd) {
+	m.series.record(winner)
+	endMessage += m.series.rate(winner)
+	endMessage += archiveGame(m.record(), winner, m.clock)
+	endGameModel := NewEndGameModel(m.width, m.height, endMessage).withRematch(m.series, m.rematch)
+	//sleep for 500 ms for better UX
+	time.Sleep(500 * time.Millisecond)
+	return endGameModel, endGameModel.Init()
+}
+
+// record describes the game so far in notation.
+func (m *UltimateModel) record() notation.Record {
+	rec := notation.New(m.state, m.series.name(game.X), m.series.name(game.O), m.started)
+	rec.Mode = archive.ModeHotSeat
+	return rec
+}
+
+// rematch starts the next game of the series, the other seat moves first.
+func (m *UltimateModel) rematch(width, height int) (tea.Model, tea.Cmd) {
+	m.series.next()
+	next := NewUltimateModel(width, height)
+	next.series = m.series
+	if m.clock != nil {
+		next.withClock(m.clock.Control())
+	}
+	return next, next.Init()
+}
+
+// moveCursor moves across the 9x9 cells, crossing between small boards.
+func (m *UltimateModel) moveCursor(deltaRow, deltaCol int) {
+	// Clear the messages when move is made
+	m.errorMessage = ""
+	m.infoMessage = ""
+	m.selectedRow = clamp(m.selectedRow+deltaRow, 0, m.state.Size()-1)
+	m.selectedCol = clamp(m.selectedCol+deltaCol, 0, m.state.Size()-1)
+}
+
+func (m *UltimateModel) View() string {
+	cursorRow, cursorCol := m.selectedRow, m.selectedCol
+	if m.reviewing {
+		cursorRow, cursorCol = -1, -1
+	}
+	board := renderUltimate(m.state, cursorRow, cursorCol, m.state.Turn().String())
+
+	currentPlayer := fmt.Sprintf("Current player: %s\n", m.state.Turn())
+	if m.series.games() > 0 {
+		currentPlayer = fmt.Sprintf("Current player: %s (%s)\n", m.state.Turn(), m.series.name(m.state.Turn()))
+	}
+	if m.reviewing {
+		currentPlayer = "Reviewing the game\n"
+	}
+	header := constants.HeaderStyle.Render(currentPlayer)
+
+	where := "Play in any open board"
+	if row, col, ok := m.state.ForcedBoard(); ok {
+		where = fmt.Sprintf("Play in the highlighted board (row %d, column %d)", row+1, col+1)
+	}
+	if m.series.games() > 0 {
+		where = m.series.String() + "\n" + where
+	}
+	info := constants.InfoStyle.Render(where)
+
+	// Quick help
+	help := "arrow keys: move | enter: select | u: undo | U: redo | tab: history | s: save | ctrl+c or Esc: quit"
+	if m.reviewing {
+		help = "arrow keys: step | home/end: first/last move | tab or enter: play from here"
+	}
+	footer := constants.SubtleStyle.Render(help)
+
+	errorMsg := ""
+	if m.errorMessage != "" {
+		errorMsg = constants.ErrorStyle.Render(m.errorMessage)
+	} else if m.infoMessage != "" {
+		errorMsg = constants.HighlightStyle.Render(m.infoMessage)
+	}
+
+	view := lipgloss.JoinVertical(lipgloss.Center,
+		header,
+		info,
+		lipgloss.JoinHorizontal(lipgloss.Center, board, renderClocks(m.clock), m.history.View(m.reviewing)),
+		errorMsg,
+		footer,
+	)
+
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, view)
+}
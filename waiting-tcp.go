@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/constants"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/game"
+	"github.com/dziedzicgrzegorz/Tic-Tac-Toe/protocol"
+)
+
+type connectedMsg struct {
+	conn    *protocol.Conn
+	session session
+	player  game.Player
+	rules   game.Rules
+}
+
+type connectFailedMsg struct{ err error }
+
+// WaitingModel shows a spinner while the connection is set up in the
+// background. Esc cancels and goes back to the form.
+type WaitingModel struct {
+	width    int
+	height   int
+	spinner  spinner.Model
+	form     TcpInputModel
+	wait     bool
+	watch    bool
+	ip       string
+	port     string
+	timeout  time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	started  time.Time
+	localIPs []string
+}
+
+func NewWaitingModel(form TcpInputModel, wait, watch bool, ip, port string, timeout time.Duration) WaitingModel {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = constants.FocusedStyle
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	m := WaitingModel{
+		width:   form.width,
+		height:  form.height,
+		spinner: s,
+		form:    form,
+		wait:    wait,
+		watch:   watch,
+		ip:      ip,
+		port:    port,
+		timeout: timeout,
+		ctx:     ctx,
+		cancel:  cancel,
+		started: time.Now(),
+	}
+	if wait {
+		m.localIPs = localAddresses()
+	}
+	return m
+}
+
+func (m WaitingModel) Init() tea.Cmd {
+	return tea.Batch(m.spinner.Tick, m.connect())
+}
+
+// connect runs the blocking setup off the UI goroutine.
+func (m WaitingModel) connect() tea.Cmd {
+	ctx, wait, ip, port, rules, control, name := m.ctx, m.wait, m.ip, m.port, m.form.rules, m.form.control, m.form.name
+	if m.watch {
+		return func() tea.Msg {
+			conn, rules, err := watchGame(ctx, ip, port)
+			if err != nil {
+				return connectFailedMsg{err: err}
+			}
+			return watchingMsg{conn: conn, rules: rules}
+		}
+	}
+	return func() tea.Msg {
+		conn, session, player, rules, err := setupConnection(ctx, wait, ip, port, rules, control, name)
+		if err != nil {
+			return connectFailedMsg{err: err}
+		}
+		return connectedMsg{conn: conn, session: session, player: player, rules: rules}
+	}
+}
+
+func (m WaitingModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case constants.CtrlC:
+			m.cancel()
+			return m, tea.Quit
+		case constants.Esc:
+			m.cancel()
+			m.form.errorMessage = "Cancelled"
+			return m.form, m.form.Init()
+		}
+
+	case connectedMsg:
+		m.cancel()
+		game := newTCPModel(m.width, m.height, msg.conn, msg.session, msg.player, msg.rules)
+		return game, game.Init()
+
+	case watchingMsg:
+		m.cancel()
+		spectator := NewSpectatorModel(m.width, m.height, msg.conn, msg.rules)
+		return spectator, spectator.Init()
+
+	case connectFailedMsg:
+		m.cancel()
+		m.form.errorMessage = formatErrorMessage(msg.err.Error())
+		if errors.Is(msg.err, context.DeadlineExceeded) {
+			m.form.errorMessage = fmt.Sprintf("Nobody connected within %s", m.timeout)
+		}
+		return m.form, m.form.Init()
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.form.width = msg.Width
+		m.form.height = msg.Height
+	}
+	return m, nil
+}
+
+func (m WaitingModel) View() string {
+	status := fmt.Sprintf("Connecting to %s:%s…", m.ip, m.port)
+	if m.watch {
+		status = fmt.Sprintf("Connecting to %s:%s to watch…", m.ip, m.port)
+	}
+	if m.wait {
+		status = fmt.Sprintf("Waiting for opponent on :%s…", m.port)
+	}
+	header := constants.HeaderStyle.Render(fmt.Sprintf("%s %s", m.spinner.View(), status))
+
+	var details []string
+	if m.wait && len(m.localIPs) > 0 {
+		details = append(details, "Share one of these addresses with your opponent:")
+		for _, ip := range m.localIPs {
+			details = append(details, constants.FocusedStyle.Render(ip))
+		}
+		details = append(details, "", "Spectators can join on the same port.")
+	}
+	remaining := m.timeout - time.Since(m.started).Truncate(time.Second)
+	details = append(details, "", fmt.Sprintf("Giving up in %s", max(remaining, 0)))
+	info := constants.InfoStyle.Render(strings.Join(details, "\n"))
+
+	footer := constants.SubtleStyle.Render("esc: cancel | ctrl+c: quit")
+
+	view := lipgloss.JoinVertical(lipgloss.Center, header, info, footer)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, view)
+}